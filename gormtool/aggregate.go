@@ -0,0 +1,262 @@
+// gormtool\aggregate.go
+package gormtool
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// AggregateFunc 聚合函数
+// Func 取值 count/sum/avg/min/max；count 的 Field 可以为空表示 COUNT(*)；
+// Alias 为结果中的列名，默认 func_field（如 sum_total）
+type AggregateFunc struct {
+	Func  string `json:"func"`
+	Field string `json:"field,omitempty"`
+	Alias string `json:"alias,omitempty"`
+}
+
+// AggregateSpec 聚合查询
+// WHERE 条件沿用 QueryBuilder，Having 中的字段可以是聚合别名或分组字段
+//
+//	{"group_by": ["user_id"], "aggregates": [{"func": "sum", "field": "total", "alias": "amount"}],
+//	 "having": {"conditions": [{"field": "amount", "operator": ">", "value": 100}]}}
+type AggregateSpec struct {
+	GroupBy    []string        `json:"group_by"`
+	Aggregates []AggregateFunc `json:"aggregates"`
+	Having     *ConditionGroup `json:"having,omitempty"`
+}
+
+var aliasPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// aggregateExpr 构建单个聚合表达式，返回别名
+func (r *fieldResolver) aggregateExpr(agg AggregateFunc) (clause.Expression, string, error) {
+	fn := strings.ToLower(strings.TrimSpace(agg.Func))
+	alias := agg.Alias
+
+	if fn == "count" && (agg.Field == "" || agg.Field == "*") {
+		if alias == "" {
+			alias = "count"
+		}
+		return clause.Expr{SQL: "COUNT(*)"}, alias, nil
+	}
+
+	field, err := r.resolve(agg.Field)
+	if err != nil {
+		return nil, "", err
+	}
+	if alias == "" {
+		alias = fn + "_" + field.DBName
+	}
+	col := clause.Column{Table: clause.CurrentTable, Name: field.DBName}
+
+	switch fn {
+	case "count", "min", "max":
+	case "sum", "avg":
+		if field.DataType != schema.Int && field.DataType != schema.Uint && field.DataType != schema.Float {
+			return nil, "", invalidQuery("%s 只能用于数值字段: %s", fn, agg.Field)
+		}
+	default:
+		return nil, "", invalidQuery("不支持的聚合函数: %s", agg.Func)
+	}
+	return clause.Expr{SQL: strings.ToUpper(fn) + "(?)", Vars: []interface{}{col}}, alias, nil
+}
+
+// Aggregate 分组聚合查询，返回每组一行，键为分组列名和聚合别名
+func (t *CRUDTool) Aggregate(ctx context.Context, model interface{}, qb *QueryBuilder, spec *AggregateSpec) (rows []map[string]interface{}, err error) {
+	start := time.Now()
+	ctx = t.startOperation(ctx, "aggregate", model)
+
+	defer func() {
+		t.logService(ctx, "aggregate", model, start, err, map[string]interface{}{
+			"rows": len(rows),
+		})
+	}()
+
+	if spec == nil || len(spec.Aggregates) == 0 {
+		err = invalidQuery("至少需要一个聚合函数")
+		return nil, err
+	}
+
+	r, err := t.newFieldResolver(model)
+	if err != nil {
+		err = wrapDBError("aggregate", "查询失败", err)
+		return nil, err
+	}
+
+	selects := make([]clause.Expression, 0, len(spec.GroupBy)+len(spec.Aggregates))
+	groupBy := make([]clause.Column, 0, len(spec.GroupBy))
+	for _, name := range spec.GroupBy {
+		col, err := r.column(name)
+		if err != nil {
+			return nil, err
+		}
+		groupBy = append(groupBy, col)
+		selects = append(selects, clause.Expr{SQL: "?", Vars: []interface{}{col}})
+	}
+
+	aggregates := make(map[string]clause.Expression, len(spec.Aggregates))
+	for _, agg := range spec.Aggregates {
+		expr, alias, err := r.aggregateExpr(agg)
+		if err != nil {
+			return nil, err
+		}
+		if !aliasPattern.MatchString(alias) {
+			return nil, invalidQuery("无效的聚合别名: %s", alias)
+		}
+		if _, dup := aggregates[alias]; dup {
+			return nil, invalidQuery("重复的聚合别名: %s", alias)
+		}
+		aggregates[alias] = expr
+		selects = append(selects, clause.Expr{SQL: "? AS ?", Vars: []interface{}{expr, clause.Column{Name: alias}}})
+	}
+
+	// WHERE 条件和预加载以外的部分沿用 BuildQuery，排序在下面按别名处理
+	base := &QueryBuilder{}
+	var sorts []SortCondition
+	if qb != nil {
+		base = &QueryBuilder{Conditions: qb.Conditions, Where: qb.Where}
+		sorts = qb.Sorts
+	}
+	db, err := t.BuildQuery(t.DB.WithContext(ctx).Model(model), model, base)
+	if err != nil {
+		err = wrapDBError("aggregate", "查询失败", err)
+		return nil, err
+	}
+
+	// 全文搜索只作为过滤条件，分组结果没有相关度
+	if qb != nil && qb.Search != "" {
+		if db, err = t.applySearch(db, model, qb.Search, false); err != nil {
+			return nil, err
+		}
+	}
+
+	// HAVING 复用条件引擎，字段名可以是聚合别名
+	groupClause := clause.GroupBy{Columns: groupBy}
+	if spec.Having != nil {
+		r.aggregates = aggregates
+		having, err := r.buildGroup(spec.Having, 1)
+		if err != nil {
+			return nil, err
+		}
+		if having != nil {
+			groupClause.Having = []clause.Expression{having}
+		}
+	}
+
+	db = db.Clauses(clause.Select{Expression: joinExprs(selects)})
+	if len(groupBy) > 0 || len(groupClause.Having) > 0 {
+		db = db.Clauses(groupClause)
+	}
+
+	for _, sort := range sorts {
+		if _, ok := aggregates[sort.Field]; ok {
+			desc, err := sortDesc(sort.Direction)
+			if err != nil {
+				return nil, err
+			}
+			db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: sort.Field}, Desc: desc})
+			continue
+		}
+		order, err := r.buildSort(sort)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Order(order)
+	}
+
+	rows = make([]map[string]interface{}, 0)
+	if err = db.Find(&rows).Error; err != nil {
+		err = wrapDBError("aggregate", "查询失败", err)
+		return nil, err
+	}
+	return rows, nil
+}
+
+// joinExprs 用逗号连接多个表达式
+func joinExprs(exprs []clause.Expression) clause.Expression {
+	placeholders := make([]string, len(exprs))
+	vars := make([]interface{}, len(exprs))
+	for i, expr := range exprs {
+		placeholders[i] = "?"
+		vars[i] = expr
+	}
+	return clause.Expr{SQL: strings.Join(placeholders, ", "), Vars: vars}
+}
+
+// ParseAggregateParams 从 URL 解析聚合参数
+//
+//	?group_by=user_id&aggregate=count,sum:total:amount&having[amount][gt]=100
+//
+// aggregate 每项格式为 func[:field[:alias]]，having 的语法与 filter 相同
+func ParseAggregateParams(values url.Values) (*AggregateSpec, error) {
+	spec := &AggregateSpec{}
+	var problems []ParamError
+
+	for _, value := range values["group_by"] {
+		spec.GroupBy = append(spec.GroupBy, splitList(value)...)
+	}
+	for _, value := range values["aggregate"] {
+		for _, item := range splitList(value) {
+			parts := strings.Split(item, ":")
+			if len(parts) > 3 {
+				problems = append(problems, ParamError{Param: "aggregate", Value: item, Reason: "格式应为 func[:field[:alias]]"})
+				continue
+			}
+			agg := AggregateFunc{Func: parts[0]}
+			if len(parts) > 1 {
+				agg.Field = parts[1]
+			}
+			if len(parts) > 2 {
+				agg.Alias = parts[2]
+			}
+			spec.Aggregates = append(spec.Aggregates, agg)
+		}
+	}
+
+	having, havingProblems := parseFilterParams(values, "having")
+	problems = append(problems, havingProblems...)
+	if len(having) > 0 {
+		spec.Having = &ConditionGroup{Conditions: having}
+	}
+
+	if len(problems) > 0 {
+		return nil, &Error{
+			Kind:    ErrKindInvalidArgument,
+			Op:      "parse_aggregate_params",
+			Message: "查询参数错误",
+			Details: problems,
+		}
+	}
+	return spec, nil
+}
+
+// GetAggregate 分组聚合查询接口，spec 为 nil 时从 URL 解析（见 ParseAggregateParams），
+// URL 中的 filter 参数作为 WHERE 条件追加到 qb 上
+func (t *CRUDTool) GetAggregate(c *gin.Context, model interface{}, qb *QueryBuilder, spec *AggregateSpec) error {
+	parsed, err := ParseQueryParams(c.Request.URL.Query())
+	if err == nil && spec == nil {
+		spec, err = ParseAggregateParams(c.Request.URL.Query())
+	}
+	if err != nil {
+		t.logService(c.Request.Context(), "aggregate", model, time.Now(), err, nil)
+		RespondError(c, err)
+		return err
+	}
+
+	rows, err := t.Aggregate(c.Request.Context(), model, qb.Merge(parsed), spec)
+	if err != nil {
+		RespondError(c, err)
+		return err
+	}
+
+	Respond(c, http.StatusOK, "查询成功", rows)
+	return nil
+}
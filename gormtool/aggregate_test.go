@@ -0,0 +1,45 @@
+// gormtool\aggregate_test.go
+package gormtool
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAggregate(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	env.seed(t, "apple", "apple", "apple pie", "pear")
+	if err := env.tool.RegisterSearch(&testItem{}, "Name"); err != nil {
+		t.Fatal(err)
+	}
+
+	spec := &AggregateSpec{
+		GroupBy:    []string{"name"},
+		Aggregates: []AggregateFunc{{Func: "sum", Field: "score", Alias: "total"}},
+	}
+	qb := &QueryBuilder{
+		Search: "apple",
+		Sorts:  []SortCondition{{Field: "total", Direction: "desc"}},
+	}
+	rows, err := env.tool.Aggregate(ctx, &testItem{}, qb, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 搜索过滤掉 pear
+	if len(rows) != 2 {
+		t.Fatalf("rows = %v, want 2 组", rows)
+	}
+	for _, row := range rows {
+		if row["name"] == "pear" {
+			t.Fatalf("搜索条件未生效: %v", rows)
+		}
+	}
+
+	for _, direction := range []string{"desc; DROP TABLE test_items", "sideways"} {
+		qb := &QueryBuilder{Sorts: []SortCondition{{Field: "total", Direction: direction}}}
+		if _, err := env.tool.Aggregate(ctx, &testItem{}, qb, spec); KindOf(err) != ErrKindInvalidArgument {
+			t.Errorf("排序方向 %q: err = %v, want invalid argument", direction, err)
+		}
+	}
+}
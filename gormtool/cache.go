@@ -0,0 +1,132 @@
+// gormtool\cache.go
+package gormtool
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrCacheMiss 缓存中没有该键（或已过期）
+var ErrCacheMiss = errors.New("gormtool: cache miss")
+
+// Cache 缓存后端
+// TTL 为 0 表示不过期；TTL 方法对不过期的键返回负数，键不存在时返回 ErrCacheMiss
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+	DeletePrefix(ctx context.Context, prefix string) error
+	TTL(ctx context.Context, key string) (time.Duration, error)
+}
+
+// CacheOverwriter 可选接口，覆盖其他实例可能已经缓存的旧值（如列表失效标签的新版本）
+// 多级缓存在 Overwrite 中通知其他实例丢弃本地副本；Set 只用于读取未命中后的回填，不发通知。
+// 未实现时使用 Set
+type CacheOverwriter interface {
+	Overwrite(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// CacheStats 可选接口，实现后统计信息会出现在 GetMetrics 中
+type CacheStats interface {
+	Stats(ctx context.Context) (interface{}, error)
+}
+
+// RedisCache 基于 Redis 的缓存
+type RedisCache struct {
+	Client *redis.Client
+}
+
+// NewRedisCache 创建 Redis 缓存
+func NewRedisCache(client *redis.Client) *RedisCache {
+	return &RedisCache{Client: client}
+}
+
+func (c *RedisCache) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := c.Client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrCacheMiss
+	}
+	return data, err
+}
+
+func (c *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.Client.Set(ctx, key, value, ttl).Err()
+}
+
+func (c *RedisCache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return c.Client.Del(ctx, keys...).Err()
+}
+
+// DeletePrefix 用 SCAN 遍历前缀匹配的键分批删除，不阻塞 Redis
+func (c *RedisCache) DeletePrefix(ctx context.Context, prefix string) error {
+	iter := c.Client.Scan(ctx, 0, escapeGlob(prefix)+"*", 500).Iterator()
+	batch := make([]string, 0, 500)
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == cap(batch) {
+			if err := c.Client.Del(ctx, batch...).Err(); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	return c.Delete(ctx, batch...)
+}
+
+func (c *RedisCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := c.Client.TTL(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	// Redis 对不存在的键返回 -2，不过期的键返回 -1
+	if ttl == -2 {
+		return 0, ErrCacheMiss
+	}
+	return ttl, nil
+}
+
+// Stats 返回 Redis INFO 信息
+func (c *RedisCache) Stats(ctx context.Context) (interface{}, error) {
+	info, err := c.Client.Info(ctx).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	// 解析 Redis 信息为更结构化的格式
+	redisStats := make(map[string]string)
+	lines := strings.Split(info, "\r\n")
+	for _, line := range lines {
+		if strings.HasPrefix(line, "#") || line == "" {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) == 2 {
+			redisStats[parts[0]] = parts[1]
+		}
+	}
+
+	return redisStats, nil
+}
+
+// escapeGlob 转义 Redis MATCH 模式中的特殊字符（缓存键中含有 *models.User 这样的类型名）
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
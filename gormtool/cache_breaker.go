@@ -0,0 +1,243 @@
+// gormtool\cache_breaker.go
+package gormtool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// 缓存熔断
+// 设置 CacheTimeout 后每次缓存调用最多等待该时间，超时视为失败；
+// 设置 CacheBreakerThreshold 后连续失败达到阈值时熔断器打开，CacheBreakerCooldown 内跳过缓存
+// 的读取和写入（直接查询数据库），冷却结束后放行一次试探调用（半开），成功则关闭，失败则重新打开。
+// 删除不会被跳过：跳过删除会在缓存恢复后读到旧数据。状态变化通过 Logger 记录，并出现在 GetMetrics 中。
+
+// ErrCacheUnavailable 熔断器打开，本次缓存调用被跳过
+var ErrCacheUnavailable = errors.New("gormtool: cache unavailable (circuit open)")
+
+// 熔断器状态
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+// DefaultCacheBreakerCooldown CacheBreakerCooldown 未设置时的冷却时间
+const DefaultCacheBreakerCooldown = 10 * time.Second
+
+// CacheBreakerStats 熔断器状态
+type CacheBreakerStats struct {
+	State       string     `json:"state"`
+	Failures    int        `json:"consecutive_failures"`
+	Opens       int64      `json:"opens"`   // 打开的次数
+	Skipped     int64      `json:"skipped"` // 因熔断跳过的调用
+	Timeouts    int64      `json:"timeouts"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastChange  *time.Time `json:"last_change,omitempty"`
+	RetryAt     *time.Time `json:"retry_at,omitempty"` // 打开状态下允许试探的时间
+	Threshold   int        `json:"threshold"`
+	CooldownSec float64    `json:"cooldown_seconds"`
+	TimeoutMs   float64    `json:"timeout_ms"`
+}
+
+// cacheBreaker 熔断器，零值为关闭状态
+type cacheBreaker struct {
+	mu         sync.Mutex
+	state      string
+	failures   int
+	openedAt   time.Time
+	probing    bool // 半开状态下已放行试探调用
+	opens      int64
+	skipped    int64
+	timeouts   int64
+	lastError  string
+	lastChange time.Time
+}
+
+// breakerTransition 一次状态变化，在锁外记录日志
+type breakerTransition struct {
+	from, to string
+	failures int
+	err      string
+}
+
+func (t *CRUDTool) breakerCooldown() time.Duration {
+	if t.CacheBreakerCooldown <= 0 {
+		return DefaultCacheBreakerCooldown
+	}
+	return t.CacheBreakerCooldown
+}
+
+// cacheAllow 判断本次调用是否放行，always 为 true 时（删除）总是放行
+func (t *CRUDTool) cacheAllow(always bool) bool {
+	if t.CacheBreakerThreshold <= 0 {
+		return true
+	}
+	b := &t.breaker
+	b.mu.Lock()
+	var tr *breakerTransition
+	allowed := true
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) >= t.breakerCooldown() {
+			tr = b.setState(BreakerHalfOpen)
+			b.probing = true
+		} else if !always {
+			b.skipped++
+			allowed = false
+		}
+	case BreakerHalfOpen:
+		if b.probing && !always {
+			b.skipped++
+			allowed = false
+		}
+		b.probing = true
+	}
+	b.mu.Unlock()
+
+	t.logBreakerTransition(tr)
+	return allowed
+}
+
+// cacheResult 记录调用结果
+// 成功和缓存未命中都说明后端可用：清零连续失败次数，半开或打开状态转为关闭；
+// 调用方取消不算成功也不算失败，但会结束半开状态下的试探，允许下一次试探
+func (t *CRUDTool) cacheResult(ctx context.Context, err error) {
+	if t.CacheBreakerThreshold <= 0 && t.CacheTimeout <= 0 {
+		return
+	}
+
+	b := &t.breaker
+	b.mu.Lock()
+	var tr *breakerTransition
+	switch {
+	case err == nil || errors.Is(err, ErrCacheMiss):
+		b.failures = 0
+		b.probing = false
+		if b.state == BreakerOpen || b.state == BreakerHalfOpen {
+			tr = b.setState(BreakerClosed)
+		}
+	case errors.Is(err, ErrCacheUnavailable) || ctx.Err() != nil:
+		b.probing = false
+	default:
+		b.failures++
+		b.lastError = err.Error()
+		if errors.Is(err, context.DeadlineExceeded) {
+			b.timeouts++
+		}
+		b.probing = false
+		switch {
+		case t.CacheBreakerThreshold <= 0:
+		case b.state == BreakerHalfOpen,
+			b.state != BreakerOpen && b.failures >= t.CacheBreakerThreshold:
+			tr = b.setState(BreakerOpen)
+			b.openedAt = time.Now()
+			b.opens++
+		}
+	}
+	b.mu.Unlock()
+
+	t.logBreakerTransition(tr)
+}
+
+// setState 修改状态，调用方需持有锁
+func (b *cacheBreaker) setState(state string) *breakerTransition {
+	from := b.state
+	if from == "" {
+		from = BreakerClosed
+	}
+	b.state = state
+	b.probing = false
+	b.lastChange = time.Now()
+	return &breakerTransition{from: from, to: state, failures: b.failures, err: b.lastError}
+}
+
+func (t *CRUDTool) logBreakerTransition(tr *breakerTransition) {
+	if tr == nil || t.Logger == nil {
+		return
+	}
+	fields := map[string]interface{}{
+		"from":     tr.from,
+		"to":       tr.to,
+		"failures": tr.failures,
+	}
+	switch tr.to {
+	case BreakerOpen:
+		fields["error"] = tr.err
+		fields["cooldown"] = t.breakerCooldown().String()
+		t.Logger.Warn(context.Background(), "缓存熔断器打开，暂停访问缓存", fields)
+	case BreakerHalfOpen:
+		t.Logger.Info(context.Background(), "缓存熔断器半开，尝试访问缓存", fields)
+	default:
+		t.Logger.Info(context.Background(), "缓存熔断器关闭，恢复访问缓存", fields)
+	}
+}
+
+// cacheCall 通过熔断器和超时执行一次缓存调用
+// 超时后立即返回 context.DeadlineExceeded，不等待后端返回（后端本身不支持 context 超时时也有效）
+func cacheCall[T any](t *CRUDTool, ctx context.Context, always bool, fn func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	if !t.cacheAllow(always) {
+		return zero, ErrCacheUnavailable
+	}
+	if t.CacheTimeout <= 0 {
+		v, err := fn(ctx)
+		t.cacheResult(ctx, err)
+		return v, err
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, t.CacheTimeout)
+	defer cancel()
+
+	type result struct {
+		v   T
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		v, err := fn(callCtx)
+		done <- result{v, err}
+	}()
+
+	var r result
+	select {
+	case r = <-done:
+	case <-callCtx.Done():
+		r = result{zero, callCtx.Err()}
+	}
+	t.cacheResult(ctx, r.err)
+	return r.v, r.err
+}
+
+// CacheBreakerStats 返回熔断器状态
+func (t *CRUDTool) CacheBreakerStats() CacheBreakerStats {
+	b := &t.breaker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stats := CacheBreakerStats{
+		State:       b.state,
+		Failures:    b.failures,
+		Opens:       b.opens,
+		Skipped:     b.skipped,
+		Timeouts:    b.timeouts,
+		LastError:   b.lastError,
+		Threshold:   t.CacheBreakerThreshold,
+		CooldownSec: t.breakerCooldown().Seconds(),
+		TimeoutMs:   durationMs(t.CacheTimeout),
+	}
+	if stats.State == "" {
+		stats.State = BreakerClosed
+	}
+	if !b.lastChange.IsZero() {
+		lastChange := b.lastChange
+		stats.LastChange = &lastChange
+	}
+	if b.state == BreakerOpen {
+		retryAt := b.openedAt.Add(t.breakerCooldown())
+		stats.RetryAt = &retryAt
+	}
+	return stats
+}
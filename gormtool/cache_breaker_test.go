@@ -0,0 +1,139 @@
+// gormtool\cache_breaker_test.go
+package gormtool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errBackend = errors.New("backend down")
+
+// breakerStep 一次缓存调用：后端返回 err，wait 为 true 时先等待冷却结束
+type breakerStep struct {
+	wait       bool
+	always     bool // 删除
+	canceled   bool // 调用方已取消
+	err        error
+	wantCalled bool
+	wantState  string // 为空时不检查
+}
+
+func TestCacheBreakerTransitions(t *testing.T) {
+	fail := breakerStep{err: errBackend, wantCalled: true}
+	tests := []struct {
+		name  string
+		steps []breakerStep
+	}{
+		{"连续失败达到阈值后打开", []breakerStep{
+			{err: errBackend, wantCalled: true, wantState: BreakerClosed},
+			{err: errBackend, wantCalled: true, wantState: BreakerOpen},
+			{wantCalled: false, wantState: BreakerOpen},
+		}},
+		{"未命中清零连续失败", []breakerStep{
+			fail,
+			{err: ErrCacheMiss, wantCalled: true, wantState: BreakerClosed},
+			{err: errBackend, wantCalled: true, wantState: BreakerClosed},
+		}},
+		{"试探成功后关闭", []breakerStep{
+			fail, fail,
+			{wait: true, wantCalled: true, wantState: BreakerClosed},
+			{wantCalled: true, wantState: BreakerClosed},
+		}},
+		{"试探失败后重新打开", []breakerStep{
+			fail, fail,
+			{wait: true, err: errBackend, wantCalled: true, wantState: BreakerOpen},
+			{wantCalled: false, wantState: BreakerOpen},
+		}},
+		{"试探未命中后关闭", []breakerStep{
+			fail, fail,
+			{wait: true, err: ErrCacheMiss, wantCalled: true, wantState: BreakerClosed},
+			{wantCalled: true, wantState: BreakerClosed},
+		}},
+		{"试探被取消后允许下一次试探", []breakerStep{
+			fail, fail,
+			{wait: true, canceled: true, wantCalled: true, wantState: BreakerHalfOpen},
+			{wantCalled: true, wantState: BreakerClosed},
+		}},
+		{"打开时删除不被跳过", []breakerStep{
+			fail, fail,
+			{always: true, err: errBackend, wantCalled: true, wantState: BreakerOpen},
+			{wantCalled: false, wantState: BreakerOpen},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool := &CRUDTool{CacheBreakerThreshold: 2, CacheBreakerCooldown: 20 * time.Millisecond}
+			for i, step := range tt.steps {
+				if step.wait {
+					time.Sleep(30 * time.Millisecond)
+				}
+				ctx, cancel := context.WithCancel(context.Background())
+				if step.canceled {
+					cancel()
+				}
+				called := false
+				_, err := cacheCall(tool, ctx, step.always, func(ctx context.Context) (struct{}, error) {
+					called = true
+					if step.canceled {
+						return struct{}{}, ctx.Err()
+					}
+					return struct{}{}, step.err
+				})
+				cancel()
+
+				if called != step.wantCalled {
+					t.Fatalf("第 %d 步: called = %v, want %v", i+1, called, step.wantCalled)
+				}
+				if !called && !errors.Is(err, ErrCacheUnavailable) {
+					t.Fatalf("第 %d 步: err = %v, want ErrCacheUnavailable", i+1, err)
+				}
+				if got := tool.CacheBreakerStats().State; step.wantState != "" && got != step.wantState {
+					t.Fatalf("第 %d 步: state = %s, want %s", i+1, got, step.wantState)
+				}
+			}
+		})
+	}
+}
+
+// 半开状态下同时只放行一次试探
+func TestCacheBreakerSingleProbe(t *testing.T) {
+	tool := &CRUDTool{CacheBreakerThreshold: 1, CacheBreakerCooldown: 10 * time.Millisecond}
+	ctx := context.Background()
+	cacheCall(tool, ctx, false, func(context.Context) (struct{}, error) { return struct{}{}, errBackend })
+	time.Sleep(20 * time.Millisecond)
+
+	var nestedErr error
+	cacheCall(tool, ctx, false, func(context.Context) (struct{}, error) {
+		_, nestedErr = cacheCall(tool, ctx, false, func(context.Context) (struct{}, error) {
+			t.Error("试探进行中不应放行第二次调用")
+			return struct{}{}, nil
+		})
+		return struct{}{}, nil
+	})
+	if !errors.Is(nestedErr, ErrCacheUnavailable) {
+		t.Fatalf("nested err = %v, want ErrCacheUnavailable", nestedErr)
+	}
+	if got := tool.CacheBreakerStats().State; got != BreakerClosed {
+		t.Fatalf("state = %s, want %s", got, BreakerClosed)
+	}
+}
+
+// 超时计为失败
+func TestCacheBreakerTimeout(t *testing.T) {
+	tool := &CRUDTool{CacheTimeout: 10 * time.Millisecond, CacheBreakerThreshold: 1}
+	_, err := cacheCall(tool, context.Background(), false, func(ctx context.Context) (struct{}, error) {
+		<-ctx.Done()
+		time.Sleep(5 * time.Millisecond)
+		return struct{}{}, nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+	stats := tool.CacheBreakerStats()
+	if stats.State != BreakerOpen || stats.Timeouts != 1 {
+		t.Fatalf("stats = %+v, want open with 1 timeout", stats)
+	}
+}
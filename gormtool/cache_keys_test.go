@@ -0,0 +1,147 @@
+// gormtool\cache_keys_test.go
+package gormtool
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 缓存管理接口：清除、查看、清除单条记录，未知表名被拒绝且不产生新的指标
+func TestCacheAdminEndpoints(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	items := env.seed(t, "a", "b")
+	r := newTestRouter()
+	r.DELETE("/admin/cache", env.tool.FlushCache)
+	r.GET("/admin/cache/:table/:id", env.tool.InspectCache)
+	r.DELETE("/admin/cache/:table/:id", env.tool.EvictCache)
+
+	for _, item := range items {
+		var loaded testItem
+		if _, err := env.tool.FindByID(ctx, &loaded, item.ID, FindOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	key := env.tool.GenerateCacheKey(&testItem{}, items[0].ID)
+
+	w, resp := doRequest(t, r, http.MethodGet, "/admin/cache/test_items/1", "")
+	data, _ := resp.Data.(map[string]interface{})
+	if w.Code != http.StatusOK || data["cached"] != true || data["key"] != key {
+		t.Fatalf("inspect = %d %+v", w.Code, resp)
+	}
+	if value, _ := data["value"].(map[string]interface{}); value["Name"] != "a" {
+		t.Fatalf("inspect value = %v", data["value"])
+	}
+
+	if w, _ := doRequest(t, r, http.MethodDelete, "/admin/cache/test_items/1", ""); w.Code != http.StatusOK {
+		t.Fatalf("evict = %d", w.Code)
+	}
+	if cached(t, env.tool, key) {
+		t.Fatal("evict 后缓存仍存在")
+	}
+	if _, resp = doRequest(t, r, http.MethodGet, "/admin/cache/test_items/1", ""); resp.Data.(map[string]interface{})["cached"] != false {
+		t.Fatalf("evict 后 inspect = %+v", resp)
+	}
+
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		if w, resp := doRequest(t, r, method, "/admin/cache/nope/1", ""); w.Code != http.StatusNotFound || resp.ErrorCode != ErrKindNotFound {
+			t.Errorf("%s 未知表名 = %d %+v", method, w.Code, resp)
+		}
+	}
+
+	for _, table := range []string{"nope", "test_items:1", "x%3Ay"} {
+		w, resp := doRequest(t, r, http.MethodDelete, "/admin/cache?table="+table, "")
+		if w.Code != http.StatusBadRequest || resp.ErrorCode != ErrKindInvalidArgument {
+			t.Errorf("flush table=%s = %d %+v", table, w.Code, resp)
+		}
+	}
+	for table := range env.tool.CacheMetrics() {
+		if table != "test_items" {
+			t.Errorf("未知表名产生了指标: %s", table)
+		}
+	}
+
+	key2 := env.tool.GenerateCacheKey(&testItem{}, items[1].ID)
+	w, resp = doRequest(t, r, http.MethodDelete, "/admin/cache?table=test_items", "")
+	if w.Code != http.StatusOK || !strings.HasSuffix(resp.Data.(map[string]interface{})["prefix"].(string), ":test_items:") {
+		t.Fatalf("flush table = %d %+v", w.Code, resp)
+	}
+	if cached(t, env.tool, key2) {
+		t.Fatal("flush 后缓存仍存在")
+	}
+
+	if err := env.tool.SetToCache(ctx, key2, items[1]); err != nil {
+		t.Fatal(err)
+	}
+	if w, _ := doRequest(t, r, http.MethodDelete, "/admin/cache", ""); w.Code != http.StatusOK || cached(t, env.tool, key2) {
+		t.Fatalf("flush namespace = %d", w.Code)
+	}
+}
+
+// 缓存中不是 JSON 的值按字符串返回，"不存在"缓存不返回 value
+func TestInspectCacheRawValues(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	r := newTestRouter()
+	r.GET("/admin/cache/:table/:id", env.tool.InspectCache)
+	env.tool.GenerateCacheKey(&testItem{}, 0) // 记录表名
+
+	key := env.tool.GenerateCacheKey(&testItem{}, 1)
+	if err := env.tool.Cache.Set(ctx, key, []byte("not json\xff"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	w, resp := doRequest(t, r, http.MethodGet, "/admin/cache/test_items/1", "")
+	data, _ := resp.Data.(map[string]interface{})
+	if w.Code != http.StatusOK || data["cached"] != true || data["value"] != "not json\uFFFD" {
+		t.Fatalf("inspect 非 JSON 值 = %d %s", w.Code, w.Body.String())
+	}
+	if ttl, _ := data["ttl_seconds"].(float64); ttl <= 0 || ttl > 60 {
+		t.Fatalf("ttl_seconds = %v", data["ttl_seconds"])
+	}
+
+	var missing testItem
+	if _, err := env.tool.FindByID(ctx, &missing, 2, FindOptions{}); err == nil {
+		t.Fatal("不存在的记录应返回错误")
+	}
+	_, resp = doRequest(t, r, http.MethodGet, "/admin/cache/test_items/2", "")
+	data, _ = resp.Data.(map[string]interface{})
+	if data["negative"] != true || data["value"] != nil {
+		t.Fatalf("inspect 不存在缓存 = %+v", resp)
+	}
+}
+
+// GetMetrics 按表返回缓存命中和未命中次数
+func TestGetMetricsCacheModels(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	items := env.seed(t, "a")
+	r := newTestRouter()
+	r.GET("/metrics/json", env.tool.GetMetrics)
+
+	for i := 0; i < 3; i++ {
+		var loaded testItem
+		if _, err := env.tool.FindByID(ctx, &loaded, items[0].ID, FindOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w, resp := doRequest(t, r, http.MethodGet, "/metrics/json", "")
+	data, _ := resp.Data.(map[string]interface{})
+	models, _ := data["cache_models"].(map[string]interface{})
+	stats, _ := models["test_items"].(map[string]interface{})
+	if w.Code != http.StatusOK || stats == nil {
+		t.Fatalf("metrics = %d %s", w.Code, w.Body.String())
+	}
+	if stats["hits"] != float64(2) || stats["misses"] != float64(1) || stats["sets"] != float64(1) {
+		t.Fatalf("test_items 缓存指标 = %v", stats)
+	}
+	if rate, _ := stats["hit_rate"].(float64); rate < 0.66 || rate > 0.67 {
+		t.Fatalf("hit_rate = %v", stats["hit_rate"])
+	}
+	if _, ok := data["database"].(map[string]interface{}); !ok {
+		t.Fatalf("database = %v", data["database"])
+	}
+}
@@ -0,0 +1,240 @@
+// gormtool\cache_list.go
+package gormtool
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/schema"
+)
+
+// 列表查询缓存
+// 键为模型加 QueryBuilder 和分页参数的规范化哈希，条目记录它依赖的标签版本：
+//   - 模型标签：该模型的任何写入都会使它的所有列表失效
+//   - 记录标签：列表中预加载的关联记录，关联记录被修改时使包含它的列表失效
+//
+// 写入时只需更新标签版本（不需要知道有哪些列表），读取时版本不一致即视为未命中。
+// 标签版本被淘汰后读取为空，与条目中记录的版本不一致，也视为未命中。
+
+// listCacheEntry 列表缓存条目
+type listCacheEntry struct {
+	Tags map[string]string `json:"tags"` // 标签 -> 写入时的版本
+	Page *Pagination       `json:"page"`
+	Data json.RawMessage   `json:"data"`
+}
+
+// modelTag 模型标签
+func (t *CRUDTool) modelTag(model interface{}) string {
+	return t.cacheModelPrefix(model) + "tag"
+}
+
+// recordTag 记录标签
+func (t *CRUDTool) recordTag(model interface{}, id interface{}) string {
+	return fmt.Sprintf("%s:%v", t.modelTag(model), id)
+}
+
+// listCacheKey 列表缓存键，params 按 JSON 序列化（map 键有序）后取哈希
+func (t *CRUDTool) listCacheKey(kind string, models interface{}, params interface{}) (string, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf("%s%s:list:%s:%s", t.cacheModelPrefix(models), t.cacheVersion(models), kind, hex.EncodeToString(sum[:16])), nil
+}
+
+// tagVersions 读取标签的当前版本，不存在的标签会初始化一个版本
+func (t *CRUDTool) tagVersions(ctx context.Context, tags []string) map[string]string {
+	versions := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		if _, ok := versions[tag]; ok {
+			continue
+		}
+		if data, err := t.cacheGet(ctx, tag); err == nil {
+			versions[tag] = string(data)
+			continue
+		}
+		version := newTagVersion()
+		t.cacheSet(ctx, tag, []byte(version), t.tagTTL())
+		versions[tag] = version
+	}
+	return versions
+}
+
+// tagTTL 标签键的过期时间，取列表缓存时间的 2 倍，避免每条写入过的记录永久留下一个标签键
+// 标签不存在视为列表缓存失效，所以标签过期只会让少量列表重新查询
+func (t *CRUDTool) tagTTL() time.Duration {
+	if t.ListCacheTTL > 0 {
+		return 2 * t.ListCacheTTL
+	}
+	return CacheTTL
+}
+
+func newTagVersion() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 36) + "." + strconv.FormatUint(rand.Uint64(), 36)
+}
+
+// bumpTags 更新标签版本，使依赖这些标签的列表缓存失效
+// 与删除记录缓存一样在事务提交后执行，并在 CacheDoubleDeleteDelay 后再更新一次
+func (t *CRUDTool) bumpTags(ctx context.Context, tags ...string) {
+	if t.Cache == nil || t.ListCacheTTL <= 0 || len(tags) == 0 {
+		return
+	}
+	bump := func(ctx context.Context) {
+		for _, tag := range tags {
+			t.cacheOverwrite(ctx, tag, []byte(newTagVersion()), t.tagTTL())
+		}
+	}
+	t.afterCommit(ctx, func(ctx context.Context) {
+		bump(ctx)
+		t.afterDelay(ctx, bump)
+	})
+}
+
+// cachedList 读取列表缓存，未命中时调用 load 查询并写入缓存
+// 模型标签的版本在查询数据库之前读取，查询期间发生的写入会使本次写入的条目立即失效
+func (t *CRUDTool) cachedList(ctx context.Context, kind string, models interface{}, params interface{}, preloads []string, load func() (*Pagination, error)) (*Pagination, bool, error) {
+	if t.Cache == nil || t.ListCacheTTL <= 0 {
+		p, err := load()
+		return p, false, err
+	}
+
+	key, err := t.listCacheKey(kind, models, params)
+	if err != nil {
+		p, err := load()
+		return p, false, err
+	}
+
+	if data, ok := t.getCacheBytes(ctx, key); ok {
+		var entry listCacheEntry
+		if json.Unmarshal(data, &entry) == nil && t.tagsValid(ctx, entry.Tags) &&
+			json.Unmarshal(entry.Data, models) == nil {
+			return entry.Page, true, nil
+		}
+	}
+
+	versions := t.tagVersions(ctx, []string{t.modelTag(models)})
+	p, err := load()
+	if err != nil {
+		return nil, false, err
+	}
+
+	rowsData, err := json.Marshal(models)
+	if err != nil {
+		return p, false, nil
+	}
+	for tag, version := range t.tagVersions(ctx, t.relatedRecordTags(ctx, models, preloads)) {
+		versions[tag] = version
+	}
+	entry, err := json.Marshal(listCacheEntry{Tags: versions, Page: p, Data: rowsData})
+	if err == nil {
+		t.cacheSet(ctx, key, entry, t.ListCacheTTL)
+	}
+	return p, false, nil
+}
+
+// tagsValid 检查条目记录的标签版本是否都是当前版本
+func (t *CRUDTool) tagsValid(ctx context.Context, tags map[string]string) bool {
+	for tag, version := range tags {
+		data, err := t.cacheGet(ctx, tag)
+		if err != nil || string(data) != version {
+			return false
+		}
+	}
+	return true
+}
+
+// relatedRecordTags 收集查询结果中预加载的关联记录的记录标签
+func (t *CRUDTool) relatedRecordTags(ctx context.Context, models interface{}, preloads []string) []string {
+	if len(preloads) == 0 {
+		return nil
+	}
+	sch, err := t.ParseSchema(models)
+	if err != nil {
+		return nil
+	}
+
+	var tags []string
+	for _, preload := range preloads {
+		current := []reflect.Value{reflect.ValueOf(models)}
+		currentSchema := sch
+		for _, name := range strings.Split(preload, ".") {
+			rel, ok := currentSchema.Relationships.Relations[name]
+			if !ok {
+				break
+			}
+			var next []reflect.Value
+			for _, v := range current {
+				next = append(next, relationValues(ctx, rel, v)...)
+			}
+			pk := rel.FieldSchema.PrioritizedPrimaryField
+			if pk == nil {
+				break
+			}
+			related := reflect.New(rel.FieldSchema.ModelType).Interface()
+			for _, v := range next {
+				if id, zero := pk.ValueOf(ctx, v); !zero {
+					tags = append(tags, t.recordTag(related, id))
+				}
+			}
+			current, currentSchema = next, rel.FieldSchema
+		}
+	}
+	return tags
+}
+
+// relationValues 返回 v（模型、模型指针或切片）上关联字段中的所有结构体值
+func relationValues(ctx context.Context, rel *schema.Relationship, v reflect.Value) []reflect.Value {
+	v = reflect.Indirect(v)
+	var values []reflect.Value
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			values = append(values, relationValues(ctx, rel, v.Index(i))...)
+		}
+	case reflect.Struct:
+		field := reflect.Indirect(rel.Field.ReflectValueOf(ctx, v))
+		switch field.Kind() {
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < field.Len(); i++ {
+				if elem := reflect.Indirect(field.Index(i)); elem.IsValid() {
+					values = append(values, elem)
+				}
+			}
+		case reflect.Struct:
+			values = append(values, field)
+		}
+	}
+	return values
+}
+
+// InvalidateRecord 清除单条记录的缓存，并使该模型的列表和包含该记录的列表失效
+// 不经过 CRUDTool 写入数据（如自定义事务）后需要手动调用
+func (t *CRUDTool) InvalidateRecord(ctx context.Context, model interface{}, id interface{}) {
+	t.DeleteFromCache(ctx, t.GenerateCacheKey(model, id))
+	t.bumpTags(ctx, t.modelTag(model), t.recordTag(model, id))
+}
+
+// invalidateRelations 使 relations 中关联模型的列表失效（保存关联时可能创建了关联记录）
+func (t *CRUDTool) invalidateRelations(ctx context.Context, model interface{}, relations ...string) {
+	if len(relations) == 0 {
+		return
+	}
+	sch, err := t.ParseSchema(model)
+	if err != nil {
+		return
+	}
+	for _, name := range relations {
+		if rel, ok := sch.Relationships.Relations[name]; ok {
+			t.bumpTags(ctx, t.modelTag(reflect.New(rel.FieldSchema.ModelType).Interface()))
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// gormtool\cache_list_test.go
+package gormtool
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// 相同查询命中列表缓存；通过 CRUDTool 写入后模型标签失效，重新查询
+func TestFindPageListCache(t *testing.T) {
+	env := newTestEnv(t)
+	env.tool.ListCacheTTL = time.Minute
+	ctx := context.Background()
+	items := env.seed(t, "a", "b")
+
+	var first []testItem
+	p, err := env.tool.FindPage(ctx, &first, &QueryBuilder{}, 1, 10)
+	if err != nil || p.Total != 2 {
+		t.Fatalf("FindPage = %+v, %v", p, err)
+	}
+	queries := env.queries.Load()
+
+	var second []testItem
+	if p, err = env.tool.FindPage(ctx, &second, &QueryBuilder{}, 1, 10); err != nil || p.Total != 2 || len(second) != 2 {
+		t.Fatalf("FindPage = %+v, %d, %v", p, len(second), err)
+	}
+	if got := env.queries.Load(); got != queries {
+		t.Fatalf("缓存命中时查询了数据库 %d 次", got-queries)
+	}
+
+	if err := env.tool.CreateRecord(ctx, &testItem{Name: "c"}); err != nil {
+		t.Fatal(err)
+	}
+	var third []testItem
+	if p, err = env.tool.FindPage(ctx, &third, &QueryBuilder{}, 1, 10); err != nil || p.Total != 3 {
+		t.Fatalf("创建后 FindPage = %+v, %v; want total 3", p, err)
+	}
+
+	err = env.tool.UpdateRecord(ctx, &testItem{}, items[0].ID, func(m interface{}) error {
+		m.(*testItem).Name = "a2"
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fourth []testItem
+	if _, err = env.tool.FindPage(ctx, &fourth, &QueryBuilder{Sorts: []SortCondition{{Field: "id", Direction: "asc"}}}, 1, 10); err != nil || fourth[0].Name != "a2" {
+		t.Fatalf("更新后 FindPage = %+v, %v", fourth, err)
+	}
+}
+
+// 预加载的关联记录被修改时，包含它的列表失效
+func TestFindPageRecordTag(t *testing.T) {
+	env := newTestEnv(t)
+	env.tool.ListCacheTTL = time.Minute
+	ctx := context.Background()
+
+	owner := testOwner{Name: "o", Items: []testItem{{Name: "a"}}}
+	if err := env.tool.DB.Create(&owner).Error; err != nil {
+		t.Fatal(err)
+	}
+	qb := &QueryBuilder{Preloads: []string{"Items"}}
+	var owners []testOwner
+	if _, err := env.tool.FindPage(ctx, &owners, qb, 1, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	err := env.tool.UpdateRecord(ctx, &testItem{}, owner.Items[0].ID, func(m interface{}) error {
+		m.(*testItem).Name = "a2"
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	owners = nil
+	if _, err := env.tool.FindPage(ctx, &owners, qb, 1, 10); err != nil {
+		t.Fatal(err)
+	}
+	if len(owners) != 1 || len(owners[0].Items) != 1 || owners[0].Items[0].Name != "a2" {
+		t.Fatalf("关联记录修改后列表未失效: %+v", owners)
+	}
+}
+
+// 标签键使用有限 TTL：ListCacheTTL 的 2 倍，未设置时为 CacheTTL
+func TestTagTTL(t *testing.T) {
+	env := newTestEnv(t)
+	env.tool.ListCacheTTL = time.Minute
+	ctx := context.Background()
+	env.seed(t, "a")
+
+	var items []testItem
+	if _, err := env.tool.FindPage(ctx, &items, &QueryBuilder{}, 1, 10); err != nil {
+		t.Fatal(err)
+	}
+	ttl, err := env.tool.Cache.TTL(ctx, env.tool.modelTag(&testItem{}))
+	if err != nil || ttl <= 0 || ttl > 2*time.Minute {
+		t.Fatalf("读取后标签 TTL = %v, %v; want (0, 2m]", ttl, err)
+	}
+
+	env.tool.InvalidateRecord(ctx, &testItem{}, uint(1))
+	ttl, err = env.tool.Cache.TTL(ctx, env.tool.recordTag(&testItem{}, uint(1)))
+	if err != nil || ttl <= 0 || ttl > 2*time.Minute {
+		t.Fatalf("失效后标签 TTL = %v, %v; want (0, 2m]", ttl, err)
+	}
+
+	if got := (&CRUDTool{}).tagTTL(); got != CacheTTL {
+		t.Fatalf("未设置 ListCacheTTL 时 tagTTL = %v, want %v", got, CacheTTL)
+	}
+}
@@ -0,0 +1,147 @@
+// gormtool\cache_memory.go
+package gormtool
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryCache 进程内 LRU 缓存，条目数超过容量时淘汰最久未使用的条目，
+// 过期条目在访问时删除。适合单节点部署和测试，多实例部署应使用 RedisCache
+type MemoryCache struct {
+	mu        sync.Mutex
+	capacity  int
+	ll        *list.List // 队首为最近使用
+	items     map[string]*list.Element
+	evictions int64
+	expired   int64
+}
+
+type memoryEntry struct {
+	key      string
+	value    []byte
+	expireAt time.Time // 零值表示不过期
+}
+
+// NewMemoryCache 创建进程内缓存，capacity 为最大条目数，<= 0 时使用 10000
+func NewMemoryCache(capacity int) *MemoryCache {
+	if capacity <= 0 {
+		capacity = 10000
+	}
+	return &MemoryCache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// lookup 返回未过期的条目，调用方需持有锁
+func (c *MemoryCache) lookup(key string) (*list.Element, bool) {
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*memoryEntry)
+	if !entry.expireAt.IsZero() && time.Now().After(entry.expireAt) {
+		c.remove(el)
+		c.expired++
+		return nil, false
+	}
+	return el, true
+}
+
+func (c *MemoryCache) remove(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*memoryEntry).key)
+}
+
+func (c *MemoryCache) Get(ctx context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.lookup(key)
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	c.ll.MoveToFront(el)
+	// 返回副本，调用方修改不影响缓存内容
+	value := el.Value.(*memoryEntry).value
+	return append([]byte(nil), value...), nil
+}
+
+func (c *MemoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	entry := &memoryEntry{key: key, value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		entry.expireAt = time.Now().Add(ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		el.Value = entry
+		c.ll.MoveToFront(el)
+		return nil
+	}
+	c.items[key] = c.ll.PushFront(entry)
+	for c.ll.Len() > c.capacity {
+		c.remove(c.ll.Back())
+		c.evictions++
+	}
+	return nil
+}
+
+func (c *MemoryCache) Delete(ctx context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		if el, ok := c.items[key]; ok {
+			c.remove(el)
+		}
+	}
+	return nil
+}
+
+func (c *MemoryCache) DeletePrefix(ctx context.Context, prefix string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, el := range c.items {
+		if strings.HasPrefix(key, prefix) {
+			c.remove(el)
+		}
+	}
+	return nil
+}
+
+func (c *MemoryCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.lookup(key)
+	if !ok {
+		return 0, ErrCacheMiss
+	}
+	entry := el.Value.(*memoryEntry)
+	if entry.expireAt.IsZero() {
+		return -1, nil
+	}
+	return time.Until(entry.expireAt), nil
+}
+
+// Stats 返回条目数、容量和淘汰次数
+func (c *MemoryCache) Stats(ctx context.Context) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return map[string]interface{}{
+		"entries":   c.ll.Len(),
+		"capacity":  c.capacity,
+		"evictions": c.evictions,
+		"expired":   c.expired,
+	}, nil
+}
@@ -0,0 +1,162 @@
+// gormtool\cache_memory_test.go
+package gormtool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// memoryStats 返回 MemoryCache.Stats 中的计数
+func memoryStats(t *testing.T, c *MemoryCache) map[string]interface{} {
+	t.Helper()
+	stats, err := c.Stats(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stats.(map[string]interface{})
+}
+
+// 超过容量时淘汰最久未使用的条目，读取和覆盖都会刷新使用顺序
+func TestMemoryCacheLRU(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(2)
+
+	c.Set(ctx, "a", []byte("1"), 0)
+	c.Set(ctx, "b", []byte("2"), 0)
+	if _, err := c.Get(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	c.Set(ctx, "c", []byte("3"), 0) // 淘汰 b
+	if _, err := c.Get(ctx, "b"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("b 应被淘汰, err = %v", err)
+	}
+
+	c.Set(ctx, "a", []byte("1'"), 0) // 覆盖不增加条目，a 成为最近使用
+	c.Set(ctx, "d", []byte("4"), 0)  // 淘汰 c
+	for key, want := range map[string]string{"a": "1'", "d": "4"} {
+		if got, err := c.Get(ctx, key); err != nil || string(got) != want {
+			t.Errorf("Get(%s) = %q, %v; want %q", key, got, err, want)
+		}
+	}
+	if _, err := c.Get(ctx, "c"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("c 应被淘汰, err = %v", err)
+	}
+
+	stats := memoryStats(t, c)
+	if stats["entries"] != 2 || stats["capacity"] != 2 || stats["evictions"] != int64(2) {
+		t.Fatalf("stats = %v", stats)
+	}
+
+	// 返回值和写入值都是副本
+	value := []byte("x")
+	c.Set(ctx, "a", value, 0)
+	value[0] = 'y'
+	got, _ := c.Get(ctx, "a")
+	got[0] = 'z'
+	if again, _ := c.Get(ctx, "a"); string(again) != "x" {
+		t.Fatalf("缓存内容被调用方修改: %q", again)
+	}
+
+	if def := NewMemoryCache(0); def.capacity != 10000 {
+		t.Fatalf("默认容量 = %d", def.capacity)
+	}
+}
+
+// 过期条目在访问时删除，TTL 返回剩余时间，不过期的条目返回 -1
+func TestMemoryCacheTTL(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(10)
+
+	c.Set(ctx, "short", []byte("1"), 20*time.Millisecond)
+	c.Set(ctx, "long", []byte("2"), time.Minute)
+	c.Set(ctx, "forever", []byte("3"), 0)
+
+	if ttl, err := c.TTL(ctx, "long"); err != nil || ttl <= 59*time.Second || ttl > time.Minute {
+		t.Fatalf("TTL(long) = %v, %v", ttl, err)
+	}
+	if ttl, err := c.TTL(ctx, "forever"); err != nil || ttl != -1 {
+		t.Fatalf("TTL(forever) = %v, %v; want -1", ttl, err)
+	}
+	if _, err := c.TTL(ctx, "missing"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("TTL(missing) err = %v", err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, err := c.Get(ctx, "short"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("过期后 Get err = %v", err)
+	}
+	if _, err := c.TTL(ctx, "short"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("过期后 TTL err = %v", err)
+	}
+	if got, err := c.Get(ctx, "long"); err != nil || string(got) != "2" {
+		t.Fatalf("Get(long) = %q, %v", got, err)
+	}
+
+	stats := memoryStats(t, c)
+	if stats["entries"] != 2 || stats["expired"] != int64(1) {
+		t.Fatalf("stats = %v", stats)
+	}
+}
+
+// Delete 删除指定的键，DeletePrefix 只删除前缀匹配的键
+func TestMemoryCacheDelete(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(10)
+	for _, key := range []string{"app:users:1", "app:users:2", "app:users_tags:1", "app:orders:1", "other:users:1"} {
+		c.Set(ctx, key, []byte("v"), 0)
+	}
+
+	if err := c.DeletePrefix(ctx, "app:users:"); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]bool{
+		"app:users:1":      false,
+		"app:users:2":      false,
+		"app:users_tags:1": true,
+		"app:orders:1":     true,
+		"other:users:1":    true,
+	} {
+		if _, err := c.Get(ctx, key); (err == nil) != want {
+			t.Errorf("DeletePrefix 后 Get(%s) err = %v, want exists=%v", key, err, want)
+		}
+	}
+
+	if err := c.Delete(ctx, "app:orders:1", "missing"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, "app:orders:1"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("Delete 后 err = %v", err)
+	}
+	if err := c.DeletePrefix(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+	if stats := memoryStats(t, c); stats["entries"] != 0 {
+		t.Fatalf("清空后 stats = %v", stats)
+	}
+}
+
+func TestNewCRUDToolWithRedis(t *testing.T) {
+	if tool := NewCRUDToolWithRedis(nil, nil, nil); tool.Cache != nil {
+		t.Fatalf("client 为 nil 时 Cache = %#v, want nil", tool.Cache)
+	}
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	tool := NewCRUDToolWithRedis(nil, client, nil)
+	rc, ok := tool.Cache.(*RedisCache)
+	if !ok || rc.Client != client {
+		t.Fatalf("Cache = %#v, want *RedisCache", tool.Cache)
+	}
+	if err := tool.Cache.Set(context.Background(), "k", []byte("v"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := mr.Get("k"); got != "v" {
+		t.Fatalf("redis 中的值 = %q", got)
+	}
+}
@@ -0,0 +1,327 @@
+// gormtool\cache_metrics.go
+package gormtool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 缓存指标
+// CRUDTool 对缓存的所有读写都经过下面的 cacheGet/cacheSet/cacheDelete 等方法，
+// 按缓存键中的表名统计。命中和未命中只统计记录和列表，列表失效标签的读取不计入。
+// 这些方法同时经过熔断器和超时（见 cache_breaker.go），被熔断跳过的调用不计入。
+
+// CacheModelStats 单个模型（表）的缓存统计
+type CacheModelStats struct {
+	Hits           int64   `json:"hits"`
+	Misses         int64   `json:"misses"`
+	HitRate        float64 `json:"hit_rate"`
+	Sets           int64   `json:"sets"`
+	Deletes        int64   `json:"deletes"`
+	Errors         int64   `json:"errors"`
+	Operations     int64   `json:"operations"`
+	TotalLatencyMs float64 `json:"total_latency_ms"`
+	AvgLatencyMs   float64 `json:"avg_latency_ms"`
+	MaxLatencyMs   float64 `json:"max_latency_ms"`
+}
+
+// cacheCounters 单个模型的计数器
+type cacheCounters struct {
+	hits, misses, sets, deletes, errors atomic.Int64
+	ops, latency, maxLatency            atomic.Int64 // 耗时单位为纳秒
+}
+
+func (c *cacheCounters) observe(elapsed time.Duration) {
+	c.ops.Add(1)
+	c.latency.Add(int64(elapsed))
+	for {
+		max := c.maxLatency.Load()
+		if int64(elapsed) <= max || c.maxLatency.CompareAndSwap(max, int64(elapsed)) {
+			return
+		}
+	}
+}
+
+func (c *cacheCounters) snapshot() CacheModelStats {
+	hits, misses, ops := c.hits.Load(), c.misses.Load(), c.ops.Load()
+	stats := CacheModelStats{
+		Hits:           hits,
+		Misses:         misses,
+		HitRate:        hitRate(hits, misses),
+		Sets:           c.sets.Load(),
+		Deletes:        c.deletes.Load(),
+		Errors:         c.errors.Load(),
+		Operations:     ops,
+		TotalLatencyMs: durationMs(time.Duration(c.latency.Load())),
+		MaxLatencyMs:   durationMs(time.Duration(c.maxLatency.Load())),
+	}
+	if ops > 0 {
+		stats.AvgLatencyMs = stats.TotalLatencyMs / float64(ops)
+	}
+	return stats
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// cacheKeyTable 从缓存键中解析表名，并判断是否是列表失效标签
+// 清除整个命名空间时前缀中没有表名，返回 "*"
+func (t *CRUDTool) cacheKeyTable(key string) (table string, tag bool) {
+	rest := strings.TrimPrefix(key, t.cacheNamespace()+":")
+	parts := strings.SplitN(rest, ":", 3)
+	if parts[0] == "" {
+		return "*", false
+	}
+	return parts[0], len(parts) > 1 && parts[1] == "tag"
+}
+
+// cacheCountersFor 返回表的计数器
+func (t *CRUDTool) cacheCountersFor(table string) *cacheCounters {
+	if c, ok := t.cacheMetrics.Load(table); ok {
+		return c.(*cacheCounters)
+	}
+	c, _ := t.cacheMetrics.LoadOrStore(table, &cacheCounters{})
+	return c.(*cacheCounters)
+}
+
+func (t *CRUDTool) cacheGet(ctx context.Context, key string) ([]byte, error) {
+	start := time.Now()
+	data, err := cacheCall(t, ctx, false, func(ctx context.Context) ([]byte, error) {
+		return t.Cache.Get(ctx, key)
+	})
+	if errors.Is(err, ErrCacheUnavailable) {
+		return nil, err
+	}
+
+	table, tag := t.cacheKeyTable(key)
+	counters := t.cacheCountersFor(table)
+	counters.observe(time.Since(start))
+	switch {
+	case err != nil && !errors.Is(err, ErrCacheMiss):
+		counters.errors.Add(1)
+	case tag:
+	case err != nil:
+		counters.misses.Add(1)
+	default:
+		counters.hits.Add(1)
+	}
+	return data, err
+}
+
+func (t *CRUDTool) cacheSet(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return t.cacheWrite(ctx, key, value, ttl, t.Cache.Set)
+}
+
+// cacheOverwrite 覆盖其他实例可能已缓存的值，后端实现 CacheOverwriter 时使用 Overwrite
+func (t *CRUDTool) cacheOverwrite(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if o, ok := t.Cache.(CacheOverwriter); ok {
+		return t.cacheWrite(ctx, key, value, ttl, o.Overwrite)
+	}
+	return t.cacheWrite(ctx, key, value, ttl, t.Cache.Set)
+}
+
+func (t *CRUDTool) cacheWrite(ctx context.Context, key string, value []byte, ttl time.Duration, set func(context.Context, string, []byte, time.Duration) error) error {
+	start := time.Now()
+	_, err := cacheCall(t, ctx, false, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, set(ctx, key, value, ttl)
+	})
+	if errors.Is(err, ErrCacheUnavailable) {
+		return err
+	}
+
+	table, _ := t.cacheKeyTable(key)
+	counters := t.cacheCountersFor(table)
+	counters.observe(time.Since(start))
+	counters.sets.Add(1)
+	if err != nil {
+		counters.errors.Add(1)
+	}
+	return err
+}
+
+func (t *CRUDTool) cacheDelete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	start := time.Now()
+	_, err := cacheCall(t, ctx, true, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, t.Cache.Delete(ctx, keys...)
+	})
+	elapsed := time.Since(start)
+
+	// 一次删除可能涉及多个表，耗时和错误计入每个表
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		table, _ := t.cacheKeyTable(key)
+		counters := t.cacheCountersFor(table)
+		counters.deletes.Add(1)
+		if seen[table] {
+			continue
+		}
+		seen[table] = true
+		counters.observe(elapsed)
+		if err != nil {
+			counters.errors.Add(1)
+		}
+	}
+	return err
+}
+
+// cacheDeletePrefix 清除前缀是管理操作，SCAN 可能耗时较长，不经过熔断器和超时
+func (t *CRUDTool) cacheDeletePrefix(ctx context.Context, prefix string) error {
+	start := time.Now()
+	err := t.Cache.DeletePrefix(ctx, prefix)
+
+	table, _ := t.cacheKeyTable(prefix)
+	counters := t.cacheCountersFor(table)
+	counters.observe(time.Since(start))
+	counters.deletes.Add(1)
+	if err != nil {
+		counters.errors.Add(1)
+	}
+	return err
+}
+
+func (t *CRUDTool) cacheKeyTTL(ctx context.Context, key string) (time.Duration, error) {
+	start := time.Now()
+	ttl, err := cacheCall(t, ctx, false, func(ctx context.Context) (time.Duration, error) {
+		return t.Cache.TTL(ctx, key)
+	})
+	if errors.Is(err, ErrCacheUnavailable) {
+		return 0, err
+	}
+
+	table, _ := t.cacheKeyTable(key)
+	counters := t.cacheCountersFor(table)
+	counters.observe(time.Since(start))
+	if err != nil && !errors.Is(err, ErrCacheMiss) {
+		counters.errors.Add(1)
+	}
+	return ttl, err
+}
+
+// CacheMetrics 返回按表名统计的缓存指标
+func (t *CRUDTool) CacheMetrics() map[string]CacheModelStats {
+	metrics := make(map[string]CacheModelStats)
+	t.cacheMetrics.Range(func(key, value interface{}) bool {
+		metrics[key.(string)] = value.(*cacheCounters).snapshot()
+		return true
+	})
+	return metrics
+}
+
+// ResetCacheMetrics 清空缓存指标
+func (t *CRUDTool) ResetCacheMetrics() {
+	t.cacheMetrics.Range(func(key, _ interface{}) bool {
+		t.cacheMetrics.Delete(key)
+		return true
+	})
+}
+
+// cacheModelType 按表名查找模型类型，模型在生成缓存键或注册资源时记录
+func (t *CRUDTool) cacheModelType(table string) (reflect.Type, bool) {
+	typ, ok := t.cacheTables.Load(table)
+	if !ok {
+		return nil, false
+	}
+	return typ.(reflect.Type), true
+}
+
+// cacheTableNames 返回已记录的表名
+func (t *CRUDTool) cacheTableNames() []string {
+	var names []string
+	t.cacheTables.Range(func(key, _ interface{}) bool {
+		names = append(names, key.(string))
+		return true
+	})
+	sort.Strings(names)
+	return names
+}
+
+// cachedRecordModel 解析缓存管理接口的 :table 参数
+func (t *CRUDTool) cachedRecordModel(c *gin.Context, operation string) (interface{}, bool) {
+	typ, ok := t.cacheModelType(c.Param("table"))
+	if !ok {
+		RespondError(c, &Error{
+			Kind:    ErrKindNotFound,
+			Op:      operation,
+			Message: "未知的表名",
+			Details: gin.H{"tables": t.cacheTableNames()},
+		})
+		return nil, false
+	}
+	return reflect.New(typ).Interface(), true
+}
+
+// InspectCache 查看单条记录的缓存内容和剩余时间
+//
+//	GET /admin/cache/:table/:id
+//
+// 返回缓存键、是否命中、是否为“不存在”缓存、剩余秒数（-1 表示不过期）和缓存的记录（不是 JSON 时为字符串）。
+// 查看不会计入命中率。
+func (t *CRUDTool) InspectCache(c *gin.Context) {
+	if t.Cache == nil {
+		RespondError(c, NewError(ErrKindNotFound, "inspect_cache", "缓存未配置", nil))
+		return
+	}
+	model, ok := t.cachedRecordModel(c, "inspect_cache")
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	key := t.GenerateCacheKey(model, c.Param("id"))
+	result := gin.H{"key": key, "cached": false}
+
+	data, err := t.Cache.Get(ctx, key)
+	if errors.Is(err, ErrCacheMiss) {
+		Respond(c, http.StatusOK, "记录未缓存", result)
+		return
+	}
+	if err != nil {
+		RespondError(c, NewError(ErrKindDB, "inspect_cache", "读取缓存失败", err))
+		return
+	}
+	result["cached"] = true
+	result["size"] = len(data)
+	result["negative"] = string(data) == string(negativeCacheValue)
+	switch {
+	case result["negative"].(bool):
+	case json.Valid(data):
+		result["value"] = json.RawMessage(data)
+	default:
+		// 其他程序写入的非 JSON 值按字符串返回，避免响应无法编码
+		result["value"] = string(data)
+	}
+	if ttl, err := t.Cache.TTL(ctx, key); err == nil {
+		if ttl < 0 {
+			result["ttl_seconds"] = -1
+		} else {
+			result["ttl_seconds"] = ttl.Seconds()
+		}
+	}
+	Respond(c, http.StatusOK, "查询成功", result)
+}
+
+// EvictCache 清除单条记录的缓存，并使相关列表失效
+//
+//	DELETE /admin/cache/:table/:id
+func (t *CRUDTool) EvictCache(c *gin.Context) {
+	model, ok := t.cachedRecordModel(c, "evict_cache")
+	if !ok {
+		return
+	}
+	id := c.Param("id")
+	t.InvalidateRecord(c.Request.Context(), model, id)
+	Respond(c, http.StatusOK, "缓存已清除", gin.H{"key": t.GenerateCacheKey(model, id)})
+}
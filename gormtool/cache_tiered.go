@@ -0,0 +1,232 @@
+// gormtool\cache_tiered.go
+package gormtool
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// TieredCache 两级缓存：L1 为进程内 MemoryCache，L2 为 Redis
+// 读取先查 L1，未命中再查 L2 并回填 L1；写入和删除同时作用于两级，
+// 并通过 Redis pub/sub 通知其他实例删除各自 L1 中的副本。
+// 订阅断线期间的消息会丢失，L1 的过期时间不超过 L1TTL，以此限制不一致的时间。
+type TieredCache struct {
+	L1      *MemoryCache
+	L2      *RedisCache
+	Channel string        // 失效通知频道
+	L1TTL   time.Duration // L1 条目最长保留时间
+
+	logger     Logger // 无法解析通知等日志，订阅 goroutine 启动前设置
+	instanceID string
+	cancel     context.CancelFunc
+	done       chan struct{}
+
+	l1Hits, l1Misses       atomic.Int64
+	l2Hits, l2Misses       atomic.Int64
+	published, received    atomic.Int64
+	publishErrs, applyErrs atomic.Int64
+}
+
+// invalidationMessage 失效通知
+type invalidationMessage struct {
+	Origin string   `json:"origin"`           // 发送实例，忽略自己发出的消息
+	Keys   []string `json:"keys,omitempty"`   // 需要删除的键
+	Prefix string   `json:"prefix,omitempty"` // 需要删除的前缀
+}
+
+// DefaultInvalidationChannel 默认失效通知频道
+const DefaultInvalidationChannel = "gormtool:cache:invalidate"
+
+// NewTieredCache 创建两级缓存并开始订阅失效通知，channel 为空时使用 DefaultInvalidationChannel，
+// l1TTL <= 0 时为 30 秒，logger 为 nil 时使用 DefaultLogger。不再使用时调用 Close 停止订阅
+func NewTieredCache(l1 *MemoryCache, l2 *RedisCache, channel string, l1TTL time.Duration, logger Logger) *TieredCache {
+	if logger == nil {
+		logger = NewDefaultLogger()
+	}
+	if channel == "" {
+		channel = DefaultInvalidationChannel
+	}
+	if l1TTL <= 0 {
+		l1TTL = 30 * time.Second
+	}
+	id := make([]byte, 8)
+	rand.Read(id)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &TieredCache{
+		L1:         l1,
+		L2:         l2,
+		Channel:    channel,
+		L1TTL:      l1TTL,
+		logger:     logger,
+		instanceID: hex.EncodeToString(id),
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+	go c.subscribe(ctx)
+	return c
+}
+
+// Close 停止订阅失效通知
+func (c *TieredCache) Close() error {
+	c.cancel()
+	<-c.done
+	return nil
+}
+
+// subscribe 接收其他实例的失效通知并删除 L1 中的副本，go-redis 会在断线后自动重连
+func (c *TieredCache) subscribe(ctx context.Context) {
+	defer close(c.done)
+
+	pubsub := c.L2.Client.Subscribe(ctx, c.Channel)
+	defer pubsub.Close()
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			var m invalidationMessage
+			if err := json.Unmarshal([]byte(msg.Payload), &m); err != nil {
+				c.applyErrs.Add(1)
+				c.logger.Warn(ctx, "无法解析缓存失效通知", map[string]interface{}{
+					"channel": c.Channel,
+					"error":   err.Error(),
+				})
+				continue
+			}
+			if m.Origin == c.instanceID {
+				continue
+			}
+			c.received.Add(1)
+			c.L1.Delete(ctx, m.Keys...)
+			if m.Prefix != "" {
+				c.L1.DeletePrefix(ctx, m.Prefix)
+			}
+		}
+	}
+}
+
+// publish 发送失效通知，失败只计数，不影响本次写入
+func (c *TieredCache) publish(ctx context.Context, m invalidationMessage) {
+	m.Origin = c.instanceID
+	data, err := json.Marshal(m)
+	if err == nil {
+		err = c.L2.Client.Publish(ctx, c.Channel, data).Err()
+	}
+	if err != nil {
+		c.publishErrs.Add(1)
+		return
+	}
+	c.published.Add(1)
+}
+
+func (c *TieredCache) Get(ctx context.Context, key string) ([]byte, error) {
+	if data, err := c.L1.Get(ctx, key); err == nil {
+		c.l1Hits.Add(1)
+		return data, nil
+	}
+	c.l1Misses.Add(1)
+
+	data, err := c.L2.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, ErrCacheMiss) {
+			c.l2Misses.Add(1)
+		}
+		return nil, err
+	}
+	c.l2Hits.Add(1)
+	c.L1.Set(ctx, key, data, c.L1TTL)
+	return data, nil
+}
+
+// Set 写入两级缓存，不通知其他实例
+// CRUDTool 只在读取未命中后回填时调用 Set，其他实例的 L1 中没有该键的有效副本；
+// 记录修改后通过 Delete 失效，覆盖旧值使用 Overwrite
+func (c *TieredCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := c.L2.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+	l1TTL := c.L1TTL
+	if ttl > 0 && ttl < l1TTL {
+		l1TTL = ttl
+	}
+	c.L1.Set(ctx, key, value, l1TTL)
+	return nil
+}
+
+// Overwrite 写入两级缓存，并通知其他实例删除旧的 L1 副本（如列表失效标签的版本）
+func (c *TieredCache) Overwrite(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := c.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+	c.publish(ctx, invalidationMessage{Keys: []string{key}})
+	return nil
+}
+
+func (c *TieredCache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	c.L1.Delete(ctx, keys...)
+	err := c.L2.Delete(ctx, keys...)
+	c.publish(ctx, invalidationMessage{Keys: keys})
+	return err
+}
+
+func (c *TieredCache) DeletePrefix(ctx context.Context, prefix string) error {
+	c.L1.DeletePrefix(ctx, prefix)
+	err := c.L2.DeletePrefix(ctx, prefix)
+	c.publish(ctx, invalidationMessage{Prefix: prefix})
+	return err
+}
+
+// TTL 返回 L2 中的剩余时间
+func (c *TieredCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return c.L2.TTL(ctx, key)
+}
+
+// Stats 返回两级命中率、失效通知计数和 L1 统计信息
+func (c *TieredCache) Stats(ctx context.Context) (interface{}, error) {
+	l1Stats, _ := c.L1.Stats(ctx)
+	l1Hits, l1Misses := c.l1Hits.Load(), c.l1Misses.Load()
+	l2Hits, l2Misses := c.l2Hits.Load(), c.l2Misses.Load()
+
+	return map[string]interface{}{
+		"instance_id": c.instanceID,
+		"l1": map[string]interface{}{
+			"hits":     l1Hits,
+			"misses":   l1Misses,
+			"hit_rate": hitRate(l1Hits, l1Misses),
+			"memory":   l1Stats,
+		},
+		"l2": map[string]interface{}{
+			"hits":     l2Hits,
+			"misses":   l2Misses,
+			"hit_rate": hitRate(l2Hits, l2Misses),
+		},
+		"invalidations": map[string]interface{}{
+			"published":      c.published.Load(),
+			"received":       c.received.Load(),
+			"publish_errors": c.publishErrs.Load(),
+			"decode_errors":  c.applyErrs.Load(),
+		},
+	}, nil
+}
+
+// hitRate 命中率，没有请求时为 0
+func hitRate(hits, misses int64) float64 {
+	if hits+misses == 0 {
+		return 0
+	}
+	return float64(hits) / float64(hits+misses)
+}
@@ -0,0 +1,112 @@
+// gormtool\cache_tiered_test.go
+package gormtool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTieredPair 返回共用一个 Redis 的两个实例
+func newTieredPair(t *testing.T) (*TieredCache, *TieredCache) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	newCache := func() *TieredCache {
+		client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		c := NewTieredCache(NewMemoryCache(100), NewRedisCache(client), "", time.Minute, nil)
+		t.Cleanup(func() {
+			c.Close()
+			client.Close()
+		})
+		return c
+	}
+	a, b := newCache(), newCache()
+	// 等待订阅建立
+	deadline := time.Now().Add(time.Second)
+	for mr.PubSubNumSub(DefaultInvalidationChannel)[DefaultInvalidationChannel] < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("订阅未建立")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return a, b
+}
+
+// l1Has L1 中是否存在 key
+func l1Has(c *TieredCache, key string) bool {
+	_, err := c.L1.Get(context.Background(), key)
+	return err == nil
+}
+
+// waitFor 等待 cond 成立，最长 1 秒
+func waitFor(t *testing.T, msg string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal(msg)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestTieredCacheInvalidation(t *testing.T) {
+	a, b := newTieredPair(t)
+	ctx := context.Background()
+
+	if err := a.Set(ctx, "k", []byte("v1"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	// b 从 L2 读取并回填 L1
+	if data, err := b.Get(ctx, "k"); err != nil || string(data) != "v1" || !l1Has(b, "k") {
+		t.Fatalf("b.Get = %q, %v", data, err)
+	}
+
+	// 回填不通知其他实例
+	if err := b.Set(ctx, "k", []byte("v1"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if !l1Has(a, "k") {
+		t.Fatal("Set 使其他实例的 L1 失效")
+	}
+
+	// 覆盖通知其他实例删除 L1 副本
+	if err := a.Overwrite(ctx, "k", []byte("v2"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "Overwrite 后 b 的 L1 未失效", func() bool { return !l1Has(b, "k") })
+	if data, err := b.Get(ctx, "k"); err != nil || string(data) != "v2" {
+		t.Fatalf("Overwrite 后 b.Get = %q, %v", data, err)
+	}
+	if !l1Has(a, "k") {
+		t.Fatal("发送实例忽略自己的通知，L1 应保留新值")
+	}
+
+	// 删除
+	if err := b.Delete(ctx, "k"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "Delete 后 a 的 L1 未失效", func() bool { return !l1Has(a, "k") })
+	if _, err := a.Get(ctx, "k"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("Delete 后 a.Get err = %v", err)
+	}
+
+	// 按前缀删除
+	a.Set(ctx, "p:1", []byte("x"), time.Minute)
+	b.Get(ctx, "p:1")
+	if err := a.DeletePrefix(ctx, "p:"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "DeletePrefix 后 b 的 L1 未失效", func() bool { return !l1Has(b, "p:1") })
+
+	stats, _ := b.Stats(ctx)
+	inv := stats.(map[string]interface{})["invalidations"].(map[string]interface{})
+	if inv["received"].(int64) < 2 {
+		t.Fatalf("invalidations = %v", inv)
+	}
+}
@@ -0,0 +1,85 @@
+// gormtool\cache_tx.go
+package gormtool
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// 事务内的缓存失效
+// WithTransaction 在事务的 context 中放入一个失效队列，事务内发起的删除缓存和更新标签版本
+// 不会立即执行，而是在提交成功后按顺序执行，回滚时丢弃。否则其他请求可能在提交前
+// 读到旧数据并重新写入缓存，或者回滚后缓存被无故清除。
+// 事务函数中应使用 tx.Statement.Context 调用 InvalidateRecord 等方法。
+// WithTransaction 总是在 t.DB 上开启独立的事务，即使在另一个事务的函数中调用，
+// 它的失效操作也在自己提交后立即执行，不等待外层事务。
+//
+// 提交后到删除之间仍有一个窗口：并发读取可能在提交前查到旧数据、在删除后写入缓存。
+// 设置 CacheDoubleDeleteDelay 后会在延迟之后再删除一次（延迟双删）来关闭这个窗口。
+
+type pendingInvalidationsKey struct{}
+
+// pendingInvalidations 事务提交后执行的缓存操作
+type pendingInvalidations struct {
+	mu  sync.Mutex
+	ops []func(ctx context.Context)
+}
+
+func (p *pendingInvalidations) add(op func(ctx context.Context)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ops = append(p.ops, op)
+}
+
+// run 执行所有操作，ctx 中的队列被屏蔽，操作会立即执行
+func (p *pendingInvalidations) run(ctx context.Context) {
+	p.mu.Lock()
+	ops := p.ops
+	p.ops = nil
+	p.mu.Unlock()
+
+	ctx = context.WithValue(ctx, pendingInvalidationsKey{}, (*pendingInvalidations)(nil))
+	for _, op := range ops {
+		op(ctx)
+	}
+}
+
+// withPendingInvalidations 返回带有新的失效队列的 context
+func withPendingInvalidations(ctx context.Context) (context.Context, *pendingInvalidations) {
+	p := &pendingInvalidations{}
+	return context.WithValue(ctx, pendingInvalidationsKey{}, p), p
+}
+
+// afterCommit 在事务中时把 op 加入队列，否则立即执行
+func (t *CRUDTool) afterCommit(ctx context.Context, op func(ctx context.Context)) {
+	if p, _ := ctx.Value(pendingInvalidationsKey{}).(*pendingInvalidations); p != nil {
+		p.add(op)
+		return
+	}
+	op(ctx)
+}
+
+// deleteCacheKeys 删除缓存键，设置了 CacheDoubleDeleteDelay 时延迟后再删除一次
+func (t *CRUDTool) deleteCacheKeys(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	err := t.cacheDelete(ctx, keys...)
+	t.afterDelay(ctx, func(ctx context.Context) {
+		t.cacheDelete(ctx, keys...)
+	})
+	return err
+}
+
+// afterDelay 在 CacheDoubleDeleteDelay 之后执行 op，未设置延迟时不执行
+// 请求结束后 ctx 会被取消，这里使用不会被取消的副本
+func (t *CRUDTool) afterDelay(ctx context.Context, op func(ctx context.Context)) {
+	if t.CacheDoubleDeleteDelay <= 0 {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	time.AfterFunc(t.CacheDoubleDeleteDelay, func() {
+		op(ctx)
+	})
+}
@@ -0,0 +1,87 @@
+// gormtool\cache_tx_test.go
+package gormtool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// cached 缓存中是否存在 key
+func cached(t *testing.T, tool *CRUDTool, key string) bool {
+	t.Helper()
+	_, err := tool.Cache.Get(context.Background(), key)
+	if err != nil && !errors.Is(err, ErrCacheMiss) {
+		t.Fatal(err)
+	}
+	return err == nil
+}
+
+// 事务内的失效在提交后执行，回滚时丢弃
+func TestWithTransactionDefersInvalidation(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	items := env.seed(t, "a")
+	key := env.tool.GenerateCacheKey(&testItem{}, items[0].ID)
+
+	var item testItem
+	if _, err := env.tool.FindByID(ctx, &item, items[0].ID, FindOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	errRollback := errors.New("rollback")
+	err := env.tool.WithTransaction(ctx, func(tx *gorm.DB) error {
+		env.tool.InvalidateRecord(tx.Statement.Context, &testItem{}, items[0].ID)
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("WithTransaction err = %v", err)
+	}
+	if !cached(t, env.tool, key) {
+		t.Fatal("回滚后缓存被清除")
+	}
+
+	err = env.tool.WithTransaction(ctx, func(tx *gorm.DB) error {
+		env.tool.InvalidateRecord(tx.Statement.Context, &testItem{}, items[0].ID)
+		if !cached(t, env.tool, key) {
+			t.Error("提交前缓存被清除")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached(t, env.tool, key) {
+		t.Fatal("提交后缓存未清除")
+	}
+}
+
+// 延迟双删清除删除后被并发读取重新写入的旧值
+func TestDoubleDelete(t *testing.T) {
+	env := newTestEnv(t)
+	env.tool.CacheDoubleDeleteDelay = 20 * time.Millisecond
+	ctx := context.Background()
+	key := env.tool.GenerateCacheKey(&testItem{}, uint(1))
+
+	if err := env.tool.SetToCache(ctx, key, testItem{ID: 1, Name: "old"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.tool.DeleteFromCache(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if cached(t, env.tool, key) {
+		t.Fatal("第一次删除未生效")
+	}
+
+	// 模拟并发读取在删除后回填旧值
+	if err := env.tool.SetToCache(ctx, key, testItem{ID: 1, Name: "old"}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if cached(t, env.tool, key) {
+		t.Fatal("延迟双删未清除回填的旧值")
+	}
+}
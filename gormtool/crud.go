@@ -5,27 +5,38 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
+	"math/rand/v2"
 	"net/http"
 	"reflect"
-	"strconv"
-	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 )
 
 // 常量定义
 const (
 	CacheTTL = 5 * time.Minute
+	// NegativeCacheTTL 记录不存在时的缓存时间，创建、恢复记录时会主动清除
+	NegativeCacheTTL = 30 * time.Second
 )
 
+// negativeCacheValue 表示记录不存在的缓存值，不是合法的 JSON，GetFromCache 会把它当作未命中
+var negativeCacheValue = []byte("gormtool:not_found")
+
 // 扩展的结构定义
+// 游标分页模式下 Page 为 0，未统计总数时 Total 为 -1
 type Pagination struct {
-	Page     int `json:"page"`
-	PageSize int `json:"pageSize"`
-	Total    int `json:"total"`
+	Page       int    `json:"page"`
+	PageSize   int    `json:"pageSize"`
+	Total      int    `json:"total"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
 }
 
 type Response struct {
@@ -33,34 +44,64 @@ type Response struct {
 	Message string      `json:"message"`
 	Data    interface{} `json:"data"`
 	Page    *Pagination `json:"page,omitempty"`
-}
-
-// QueryCondition 查询条件结构
-type QueryCondition struct {
-	Field    string      `json:"field"`
-	Operator string      `json:"operator"` // =, !=, >, <, >=, <=, LIKE, IN, NOT IN, BETWEEN
-	Value    interface{} `json:"value"`
-}
-
-// SortCondition 排序条件
-type SortCondition struct {
-	Field     string `json:"field"`
-	Direction string `json:"direction"` // ASC, DESC
-}
-
-// QueryBuilder 查询构建器
-type QueryBuilder struct {
-	Conditions []QueryCondition `json:"conditions"`
-	Sorts      []SortCondition  `json:"sorts"`
-	Preloads   []string         `json:"preloads"`
+	// 错误类型（如 not_found、internal_error），仅错误响应有
+	ErrorCode ErrorKind `json:"error_code,omitempty"`
+	// 请求 ID，使用 RequestIDMiddleware 时填充
+	RequestID string `json:"request_id,omitempty"`
 }
 
 // CRUDTool 扩展的 CRUD 工具
 type CRUDTool struct {
-	DB          *gorm.DB
-	RedisClient *redis.Client
-	Logger      Logger
-	EnableLog   bool
+	DB        *gorm.DB
+	Cache     Cache // 为 nil 时不使用缓存
+	Logger    Logger
+	EnableLog bool
+	// 成功日志的采样比例，键为 operation（"*" 为其他操作的默认值），如 {"get_by_id": 0.1}；
+	// 未配置的操作全部记录，失败日志总是记录，采样记录的日志带有 sample_rate 字段
+	LogSampling map[string]float64
+
+	// 缓存键命名空间，如 "eco_back:prod"，为空时使用 DefaultCacheNamespace
+	CacheNamespace string
+
+	// 缓存过期抖动比例，0.1 表示实际 TTL 在 CacheTTL ±10% 内随机，避免同时写入的键同时过期
+	CacheTTLJitter float64
+	// 提前刷新系数（XFetch 算法的 beta），> 0 时缓存快过期的记录会按概率提前重新加载，
+	// 越接近过期、加载越慢的记录越容易被刷新，常用值为 1
+	CacheEarlyRefreshBeta float64
+	// 列表查询（GetByQueryBuilder）缓存时间，0 表示不缓存列表，见 cache_list.go
+	ListCacheTTL time.Duration
+	// 延迟双删的延迟时间，> 0 时删除缓存后经过该时间再删除一次，
+	// 清除并发读取在数据库写入前查到旧数据、在删除后写回缓存的条目，见 cache_tx.go
+	CacheDoubleDeleteDelay time.Duration
+	// 单次缓存调用的超时时间，0 表示不限制；超时视为失败，直接查询数据库
+	CacheTimeout time.Duration
+	// 连续失败多少次后熔断（跳过缓存 CacheBreakerCooldown），0 表示不熔断，见 cache_breaker.go
+	CacheBreakerThreshold int
+	CacheBreakerCooldown  time.Duration
+
+	// 链路追踪使用的 TracerProvider，为 nil 时使用 otel 全局 TracerProvider，见 tracing.go
+	TracerProvider trace.TracerProvider
+
+	// SQLLogger 的慢查询阈值，0 表示 DefaultSlowQueryThreshold，见 gorm_logger.go
+	SlowQueryThreshold time.Duration
+	// 慢查询日志中附带 EXPLAIN QUERY PLAN 的结果（仅 SQLite 的 SELECT 语句），仅用于开发调试：
+	// 语句是 GORM 把参数拼接进 SQL 后的日志文本，与实际执行的参数绑定语句可能不完全一致，
+	// 并且每条慢查询都会额外执行一次 EXPLAIN
+	ExplainSlowQueries bool
+	// 不按归一化 SQL 统计耗时，省去每条语句的归一化和调用位置查找，SlowestQueries 返回空
+	DisableSQLStats bool
+
+	fieldPolicies sync.Map // 按模型结构体类型保存的查询字段策略 map[reflect.Type]FieldPolicy
+	searchIndexes sync.Map // 开启全文搜索的模型 map[reflect.Type]*searchIndex
+	searchOnce    sync.Once
+	flight        flightGroup // GetByID 按缓存键合并并发查询
+	cacheVersions sync.Map    // 按模型类型缓存的结构版本 map[reflect.Type]string
+	loadDurations sync.Map    // 按模型类型记录最近一次数据库加载耗时，用于提前刷新 map[string]time.Duration
+	cacheMetrics  sync.Map    // 按表名统计的缓存指标 map[string]*cacheCounters
+	cacheTables   sync.Map    // 表名 -> 模型类型，缓存管理接口按表名查找模型 map[string]reflect.Type
+	breaker       cacheBreaker
+	metrics       operationMetrics // Prometheus 指标，见 metrics_prometheus.go
+	sqlStats      sqlStatsRegistry // 按归一化 SQL 的统计，见 gorm_logger.go
 }
 
 // DatabaseStats 数据库统计信息结构体
@@ -76,17 +117,27 @@ type DatabaseStats struct {
 }
 
 // NewCRUDTool 创建新的 CRUD 工具
-func NewCRUDTool(db *gorm.DB, redisClient *redis.Client, logger Logger) *CRUDTool {
+// cache 可以是 NewRedisCache、NewMemoryCache 或自定义实现，为 nil 时不使用缓存
+func NewCRUDTool(db *gorm.DB, cache Cache, logger Logger) *CRUDTool {
 	if logger == nil {
 		logger = NewDefaultLogger()
 	}
 
 	return &CRUDTool{
-		DB:          db,
-		RedisClient: redisClient,
-		Logger:      logger,
-		EnableLog:   true,
+		DB:        db,
+		Cache:     cache,
+		Logger:    logger,
+		EnableLog: true,
+	}
+}
+
+// NewCRUDToolWithRedis 使用 Redis 缓存创建 CRUD 工具，client 为 nil 时不使用缓存
+// 等价于 NewCRUDTool(db, NewRedisCache(client), logger)，兼容 NewCRUDTool 接收 *redis.Client 时的调用方式
+func NewCRUDToolWithRedis(db *gorm.DB, client *redis.Client, logger Logger) *CRUDTool {
+	if client == nil {
+		return NewCRUDTool(db, nil, logger)
 	}
+	return NewCRUDTool(db, NewRedisCache(client), logger)
 }
 
 // 添加日志 辅助方法
@@ -97,15 +148,26 @@ func NewCRUDTool(db *gorm.DB, redisClient *redis.Client, logger Logger) *CRUDToo
 // duration: 操作耗时
 // err: 操作错误
 // additionalFields: 额外字段
+// 同时记录 Prometheus 操作次数、耗时和错误类型指标（不受 EnableLog 影响）
+// 日志字段包含 ctx 中的请求 ID、路由、trace_id 等字段，见 request_context.go
 // 日志记录示例
 //
 //	t.LogOperation(c.Request.Context(), "get_by_id", &User{}, time.Since(start), err, map[string]interface{}{
 //		"user_id": c.Param("id"),
 //	})
 func (t *CRUDTool) LogOperation(ctx context.Context, operation string, model interface{}, duration time.Duration, err error, additionalFields map[string]interface{}) {
+	t.observeOperation(operation, model, duration, err)
+	finishOperation(ctx, operation, err, additionalFields)
 	if !t.EnableLog {
 		return
 	}
+	sampleRate := 1.0
+	if err == nil {
+		var keep bool
+		if keep, sampleRate = t.sampleSuccess(operation); !keep {
+			return
+		}
+	}
 
 	fields := map[string]interface{}{
 		"operation": operation,
@@ -117,9 +179,15 @@ func (t *CRUDTool) LogOperation(ctx context.Context, operation string, model int
 		fields["error"] = err.Error()
 	}
 
+	for k, v := range ContextLogFields(ctx) {
+		fields[k] = v
+	}
 	for k, v := range additionalFields {
 		fields[k] = v
 	}
+	if sampleRate < 1 {
+		fields["sample_rate"] = sampleRate
+	}
 
 	if err != nil {
 		t.Logger.Error(ctx, "操作失败", fields)
@@ -132,394 +200,41 @@ func (t *CRUDTool) LogOperation(ctx context.Context, operation string, model int
 type TxFunc func(tx *gorm.DB) error
 
 // WithTransaction 执行事务
+// 事务内发起的缓存失效在提交成功后执行，回滚时丢弃，见 cache_tx.go
 func (t *CRUDTool) WithTransaction(ctx context.Context, fn TxFunc) error {
-	return t.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		return fn(tx)
-	})
-}
+	txCtx, span := t.tracer().Start(ctx, "gorm.transaction")
+	defer span.End()
 
-// Transaction 事务包装器
-func (t *CRUDTool) Transaction(c *gin.Context, fn TxFunc) {
-	start := time.Now()
-	var err error
-
-	defer func() {
-		t.LogOperation(c.Request.Context(), "transaction", nil, time.Since(start), err, nil)
-	}()
-
-	err = t.WithTransaction(c.Request.Context(), fn)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, Response{
-			Code:    http.StatusInternalServerError,
-			Message: "事务执行失败",
-		})
-		return
-	}
-
-	c.JSON(http.StatusOK, Response{
-		Code:    http.StatusOK,
-		Message: "操作成功",
-	})
-}
-
-// GetByIDWithRelations 根据ID查询单条记录（支持预加载关系）
-func (t *CRUDTool) GetByIDWithRelations(c *gin.Context, model interface{}, relations []string) error {
-	start := time.Now()
-	var err error
-
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		t.LogOperation(c.Request.Context(), "get_by_id", model, time.Since(start), err, map[string]interface{}{
-			"error_type": "invalid_id",
-			"id":         c.Param("id"),
-		})
-		c.JSON(http.StatusBadRequest, Response{
-			Code:    http.StatusBadRequest,
-			Message: "无效的ID",
-		})
-		return err
-	}
-
-	// 构建查询
-	db := t.DB
-	for _, relation := range relations {
-		db = db.Preload(relation)
-	}
-
-	if err := db.First(model, id).Error; err != nil {
-		t.LogOperation(c.Request.Context(), "get_by_id", model, time.Since(start), err, map[string]interface{}{
-			"id": id,
-		})
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, Response{
-				Code:    http.StatusNotFound,
-				Message: "记录不存在",
-			})
-		} else {
-			c.JSON(http.StatusInternalServerError, Response{
-				Code:    http.StatusInternalServerError,
-				Message: "查询失败",
-			})
-		}
-		return err
-	}
-
-	t.LogOperation(c.Request.Context(), "get_by_id", model, time.Since(start), nil, map[string]interface{}{
-		"id":        id,
-		"relations": relations,
-	})
-
-	c.JSON(http.StatusOK, Response{
-		Code:    http.StatusOK,
-		Message: "查询成功",
-		Data:    model,
-	})
-	return nil
-}
-
-// CreateWithRelations 创建记录（支持关联创建）
-func (t *CRUDTool) CreateWithRelations(c *gin.Context, model interface{}, relations []string) error {
-	start := time.Now()
-	var err error
-
-	if err := c.ShouldBindJSON(model); err != nil {
-		t.LogOperation(c.Request.Context(), "create", model, time.Since(start), err,
-			map[string]interface{}{"error_type": "bind_error"})
-		c.JSON(http.StatusBadRequest, Response{
-			Code:    http.StatusBadRequest,
-			Message: "参数错误",
-		})
-		return err
-	}
-
-	err = t.WithTransaction(c.Request.Context(), func(tx *gorm.DB) error {
-		// 先创建主记录
-		if err := tx.Create(model).Error; err != nil {
-			return err
-		}
-
-		// 逐条追加关联
-		for _, rel := range relations {
-			field := reflect.Indirect(reflect.ValueOf(model)).FieldByName(rel)
-			if !field.IsValid() {
-				return fmt.Errorf("invalid relation field: %s", rel)
-			}
-			assoc := tx.Model(model).Association(rel)
-			if assoc == nil {
-				return fmt.Errorf("association %s not found", rel)
-			}
-			if err := assoc.Replace(field.Interface()); err != nil {
-				return err
-			}
-		}
-		return nil
-	})
-
-	if err != nil {
-		t.LogOperation(c.Request.Context(), "create", model, time.Since(start), err,
-			map[string]interface{}{"relations": relations})
-		c.JSON(http.StatusInternalServerError, Response{
-			Code:    http.StatusInternalServerError,
-			Message: "创建失败",
-		})
-		return err
-	}
-
-	t.LogOperation(c.Request.Context(), "create", model, time.Since(start), nil,
-		map[string]interface{}{"relations": relations})
-
-	c.JSON(http.StatusCreated, Response{
-		Code:    http.StatusCreated,
-		Message: "创建成功",
-		Data:    model,
-	})
-	return nil
-}
-
-// UpdateWithRelations 更新记录（支持关联更新）
-func (t *CRUDTool) UpdateWithRelations(c *gin.Context, model interface{}, relations []string) error {
-	start := time.Now()
-	var err error
-
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		t.LogOperation(c.Request.Context(), "update", model, time.Since(start), err, map[string]interface{}{
-			"error_type": "invalid_id",
-		})
-		c.JSON(http.StatusBadRequest, Response{
-			Code:    http.StatusBadRequest,
-			Message: "无效的ID",
-		})
-		return err
-	}
-
-	// 先检查记录是否存在
-	if err := t.DB.First(model, id).Error; err != nil {
-		t.LogOperation(c.Request.Context(), "update", model, time.Since(start), err, map[string]interface{}{
-			"id": id,
-		})
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, Response{
-				Code:    http.StatusNotFound,
-				Message: "记录不存在",
-			})
-		} else {
-			c.JSON(http.StatusInternalServerError, Response{
-				Code:    http.StatusInternalServerError,
-				Message: "查询失败",
-			})
-		}
-		return err
-	}
-
-	if err := c.ShouldBindJSON(model); err != nil {
-		t.LogOperation(c.Request.Context(), "update", model, time.Since(start), err, map[string]interface{}{
-			"error_type": "bind_error",
-		})
-		c.JSON(http.StatusBadRequest, Response{
-			Code:    http.StatusBadRequest,
-			Message: "参数错误",
-		})
-		return err
-	}
-
-	err = t.WithTransaction(c.Request.Context(), func(tx *gorm.DB) error {
-		if err := tx.Save(model).Error; err != nil {
-			return err
-		}
-
-		for _, rel := range relations {
-			// 通过反射拿到对应字段的值
-			field := reflect.Indirect(reflect.ValueOf(model)).FieldByName(rel)
-			if !field.IsValid() {
-				return fmt.Errorf("invalid relation field: %s", rel)
-			}
-			if err := tx.Model(model).Association(rel).Replace(field.Interface()); err != nil {
-				return err
-			}
-		}
-		return nil
-	})
-
-	if err != nil {
-		t.LogOperation(c.Request.Context(), "update", model, time.Since(start), err, map[string]interface{}{
-			"id":        id,
-			"relations": relations,
-		})
-		c.JSON(http.StatusInternalServerError, Response{
-			Code:    http.StatusInternalServerError,
-			Message: "更新失败",
-		})
-		return err
-	}
-
-	// 清除缓存
-	cacheKey := t.GenerateCacheKey(model, id)
-	t.DeleteFromCache(c.Request.Context(), cacheKey)
-
-	t.LogOperation(c.Request.Context(), "update", model, time.Since(start), nil, map[string]interface{}{
-		"id":        id,
-		"relations": relations,
-	})
-
-	c.JSON(http.StatusOK, Response{
-		Code:    http.StatusOK,
-		Message: "更新成功",
-		Data:    model,
-	})
-	return nil
-}
-
-// GetRelated 获取关联记录
-func (t *CRUDTool) GetRelated(c *gin.Context, model interface{}, associationName string, result interface{}) error {
-	start := time.Now()
-	var err error
-
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		t.LogOperation(c.Request.Context(), "get_related", model, time.Since(start), err, map[string]interface{}{
-			"error_type": "invalid_id",
-		})
-		c.JSON(http.StatusBadRequest, Response{
-			Code:    http.StatusBadRequest,
-			Message: "无效的ID",
-		})
-		return err
-	}
-
-	// 先获取主记录
-	if err := t.DB.First(model, id).Error; err != nil {
-		t.LogOperation(c.Request.Context(), "get_related", model, time.Since(start), err, map[string]interface{}{
-			"id": id,
-		})
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, Response{
-				Code:    http.StatusNotFound,
-				Message: "记录不存在",
-			})
-		} else {
-			c.JSON(http.StatusInternalServerError, Response{
-				Code:    http.StatusInternalServerError,
-				Message: "查询失败",
-			})
-		}
-		return err
-	}
-
-	// 获取关联记录
-	if err := t.DB.Model(model).Association(associationName).Find(result); err != nil {
-		t.LogOperation(c.Request.Context(), "get_related", model, time.Since(start), err, map[string]interface{}{
-			"id":          id,
-			"association": associationName,
-		})
-		c.JSON(http.StatusInternalServerError, Response{
-			Code:    http.StatusInternalServerError,
-			Message: "获取关联记录失败",
-		})
-		return err
-	}
-
-	t.LogOperation(c.Request.Context(), "get_related", model, time.Since(start), nil, map[string]interface{}{
-		"id":          id,
-		"association": associationName,
-	})
-
-	c.JSON(http.StatusOK, Response{
-		Code:    http.StatusOK,
-		Message: "获取关联记录成功",
-		Data:    result,
+	txCtx, pending := withPendingInvalidations(txCtx)
+	err := t.DB.WithContext(txCtx).Transaction(func(tx *gorm.DB) error {
+		return fn(tx)
 	})
-	return nil
-}
-
-// AddRelation 添加关联关系
-func (t *CRUDTool) AddRelation(c *gin.Context, model interface{}, associationName string, relatedModel interface{}) error {
-	start := time.Now()
-	var err error
-
-	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		t.LogOperation(c.Request.Context(), "add_relation", model, time.Since(start), err, map[string]interface{}{
-			"error_type": "invalid_id",
-		})
-		c.JSON(http.StatusBadRequest, Response{
-			Code:    http.StatusBadRequest,
-			Message: "无效的ID",
-		})
-		return err
-	}
-
-	if err := c.ShouldBindJSON(relatedModel); err != nil {
-		t.LogOperation(c.Request.Context(), "add_relation", model, time.Since(start), err, map[string]interface{}{
-			"error_type": "bind_error",
-		})
-		c.JSON(http.StatusBadRequest, Response{
-			Code:    http.StatusBadRequest,
-			Message: "参数错误",
-		})
-		return err
-	}
-
-	// 先获取主记录
-	if err := t.DB.First(model, id).Error; err != nil {
-		t.LogOperation(c.Request.Context(), "add_relation", model, time.Since(start), err, map[string]interface{}{
-			"id": id,
-		})
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, Response{
-				Code:    http.StatusNotFound,
-				Message: "记录不存在",
-			})
-		} else {
-			c.JSON(http.StatusInternalServerError, Response{
-				Code:    http.StatusInternalServerError,
-				Message: "查询失败",
-			})
-		}
-		return err
-	}
-
-	// 添加关联
-	if err := t.DB.Model(model).Association(associationName).Append(relatedModel); err != nil {
-		t.LogOperation(c.Request.Context(), "add_relation", model, time.Since(start), err, map[string]interface{}{
-			"id":          id,
-			"association": associationName,
-		})
-		c.JSON(http.StatusInternalServerError, Response{
-			Code:    http.StatusInternalServerError,
-			Message: "添加关联失败",
-		})
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 		return err
 	}
-
-	t.LogOperation(c.Request.Context(), "add_relation", model, time.Since(start), nil, map[string]interface{}{
-		"id":          id,
-		"association": associationName,
-	})
-
-	c.JSON(http.StatusOK, Response{
-		Code:    http.StatusOK,
-		Message: "添加关联成功",
-	})
+	pending.run(ctx)
 	return nil
 }
 
 // 缓存相关方法
+// GenerateCacheKey 生成单条记录的缓存键，格式见 cache_keys.go
 func (t *CRUDTool) GenerateCacheKey(model interface{}, id interface{}) string {
-	return fmt.Sprintf("%T:%v", model, id)
+	return fmt.Sprintf("%s%s:%v", t.cacheModelPrefix(model), t.cacheVersion(model), id)
 }
 
 func (t *CRUDTool) GetFromCache(ctx context.Context, key string, result interface{}) bool {
-	if t.RedisClient == nil {
+	if t.Cache == nil {
 		return false
 	}
 
-	data, err := t.RedisClient.Get(ctx, key).Result()
-	if err != nil {
+	data, ok := t.getCacheBytes(ctx, key)
+	if !ok {
 		return false
 	}
 
-	if err := json.Unmarshal([]byte(data), result); err != nil {
+	if err := json.Unmarshal(data, result); err != nil {
 		return false
 	}
 
@@ -527,7 +242,7 @@ func (t *CRUDTool) GetFromCache(ctx context.Context, key string, result interfac
 }
 
 func (t *CRUDTool) SetToCache(ctx context.Context, key string, data interface{}) error {
-	if t.RedisClient == nil {
+	if t.Cache == nil {
 		return nil
 	}
 
@@ -536,497 +251,116 @@ func (t *CRUDTool) SetToCache(ctx context.Context, key string, data interface{})
 		return err
 	}
 
-	return t.RedisClient.Set(ctx, key, jsonData, CacheTTL).Err()
+	return t.setCacheBytes(ctx, key, jsonData)
 }
 
-func (t *CRUDTool) DeleteFromCache(ctx context.Context, key string) error {
-	if t.RedisClient == nil {
-		return nil
-	}
-
-	return t.RedisClient.Del(ctx, key).Err()
-}
-
-// 查询构建器方法
-func (t *CRUDTool) BuildQuery(db *gorm.DB, qb *QueryBuilder) *gorm.DB {
-	if qb == nil {
-		return db
-	}
-
-	// 构建条件
-	for _, cond := range qb.Conditions {
-		switch cond.Operator {
-		case "=", "!=", ">", "<", ">=", "<=":
-			db = db.Where(fmt.Sprintf("%s %s ?", cond.Field, cond.Operator), cond.Value)
-		case "LIKE":
-			db = db.Where(fmt.Sprintf("%s LIKE ?", cond.Field), "%"+cond.Value.(string)+"%")
-		case "IN":
-			db = db.Where(fmt.Sprintf("%s IN (?)", cond.Field), cond.Value)
-		case "NOT IN":
-			db = db.Where(fmt.Sprintf("%s NOT IN (?)", cond.Field), cond.Value)
-		case "BETWEEN":
-			if values, ok := cond.Value.([]interface{}); ok && len(values) == 2 {
-				db = db.Where(fmt.Sprintf("%s BETWEEN ? AND ?", cond.Field), values[0], values[1])
-			}
-		}
-	}
-
-	// 构建排序
-	for _, sort := range qb.Sorts {
-		db = db.Order(fmt.Sprintf("%s %s", sort.Field, sort.Direction))
-	}
-
-	// 构建预加载
-	for _, preload := range qb.Preloads {
-		db = db.Preload(preload)
+func (t *CRUDTool) getCacheBytes(ctx context.Context, key string) ([]byte, bool) {
+	if t.Cache == nil {
+		return nil, false
 	}
-
-	return db
-}
-
-// 核心 CRUD 方法（带缓存和监控）
-func (t *CRUDTool) GetByID(c *gin.Context, model interface{}, preloads ...string) error {
-	start := time.Now()
-	var err error
-
-	defer func() {
-		t.LogOperation(c.Request.Context(), "get_by_id", model, time.Since(start), err, nil)
-	}()
-
-	id, err := strconv.Atoi(c.Param("id"))
+	data, err := t.cacheGet(ctx, key)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, Response{
-			Code:    http.StatusBadRequest,
-			Message: "无效的ID",
-		})
-		return err
+		return nil, false
 	}
-
-	// 尝试从缓存获取
-	cacheKey := t.GenerateCacheKey(model, id)
-	if t.GetFromCache(c.Request.Context(), cacheKey, model) {
-		c.JSON(http.StatusOK, Response{
-			Code:    http.StatusOK,
-			Message: "查询成功（缓存）",
-			Data:    model,
-		})
-		return nil
-	}
-
-	db := t.DB
-	for _, preload := range preloads {
-		db = db.Preload(preload)
-	}
-
-	if err := db.First(model, id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, Response{
-				Code:    http.StatusNotFound,
-				Message: "记录不存在",
-			})
-		} else {
-			c.JSON(http.StatusInternalServerError, Response{
-				Code:    http.StatusInternalServerError,
-				Message: "查询失败",
-			})
-		}
-		return err
-	}
-
-	// 设置缓存
-	t.SetToCache(c.Request.Context(), cacheKey, model)
-
-	c.JSON(http.StatusOK, Response{
-		Code:    http.StatusOK,
-		Message: "查询成功",
-		Data:    model,
-	})
-	return nil
+	return data, true
 }
 
-// GetByIDWithSoftDelete 支持软删除的查询
-func (t *CRUDTool) GetByIDWithSoftDelete(c *gin.Context, model interface{}, preloads ...string) error {
-	start := time.Now()
-	var err error
-
-	defer func() {
-		t.LogOperation(c.Request.Context(), "get_by_id_soft_delete", model, time.Since(start), err, nil)
-	}()
-
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, Response{
-			Code:    http.StatusBadRequest,
-			Message: "无效的ID",
-		})
-		return err
-	}
-
-	db := t.DB.Unscoped() // 包含已删除的记录
-	for _, preload := range preloads {
-		db = db.Preload(preload)
-	}
-
-	if err := db.First(model, id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, Response{
-				Code:    http.StatusNotFound,
-				Message: "记录不存在",
-			})
-		} else {
-			c.JSON(http.StatusInternalServerError, Response{
-				Code:    http.StatusInternalServerError,
-				Message: "查询失败",
-			})
-		}
-		return err
+func (t *CRUDTool) setCacheBytes(ctx context.Context, key string, data []byte) error {
+	if t.Cache == nil {
+		return nil
 	}
-
-	c.JSON(http.StatusOK, Response{
-		Code:    http.StatusOK,
-		Message: "查询成功",
-		Data:    model,
-	})
-	return nil
+	return t.cacheSet(ctx, key, data, t.cacheTTL())
 }
 
-// GetByQueryBuilder 使用查询构建器（支持分页）
-func (t *CRUDTool) GetByQueryBuilder(c *gin.Context, models interface{}, qb *QueryBuilder) error {
-	start := time.Now()
-	var err error
-
-	defer func() {
-		t.LogOperation(c.Request.Context(), "get_by_query_builder", models, time.Since(start), err, nil)
-	}()
-
-	// 分页参数处理
-	pageStr := c.DefaultQuery("page", "1")
-	pageSizeStr := c.DefaultQuery("pagesize", "10")
-	page, _ := strconv.Atoi(pageStr)
-	pageSize, _ := strconv.Atoi(pageSizeStr)
-	if page < 1 {
-		page = 1
-	}
-	if pageSize < 1 {
-		pageSize = 10
-	}
-
-	db := t.BuildQuery(t.DB, qb)
-
-	// 获取总数
-	var total int64
-	if err := db.Model(models).Count(&total).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, Response{
-			Code:    http.StatusInternalServerError,
-			Message: "查询失败",
-		})
-		return err
-	}
-
-	// 分页查询
-	offset := (page - 1) * pageSize
-	if err := db.Limit(pageSize).Offset(offset).Find(models).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, Response{
-			Code:    http.StatusInternalServerError,
-			Message: "查询失败",
-		})
-		return err
+// setNegativeCache 缓存记录不存在，防止反复查询不存在的 ID 打到数据库
+func (t *CRUDTool) setNegativeCache(ctx context.Context, key string) error {
+	if t.Cache == nil {
+		return nil
 	}
-
-	c.JSON(http.StatusOK, Response{
-		Code:    http.StatusOK,
-		Message: "查询成功",
-		Data:    models,
-		Page: &Pagination{
-			Page:     page,
-			PageSize: pageSize,
-			Total:    int(total),
-		},
-	})
-	return nil
+	return t.cacheSet(ctx, key, negativeCacheValue, NegativeCacheTTL)
 }
 
-// Create 创建记录（带缓存失效）
-func (t *CRUDTool) Create(c *gin.Context, model interface{}) error {
-	start := time.Now()
-	var err error
-
-	defer func() {
-		t.LogOperation(c.Request.Context(), "create", model, time.Since(start), err, nil)
-	}()
-
-	if err := c.ShouldBindJSON(model); err != nil {
-		c.JSON(http.StatusBadRequest, Response{
-			Code:    http.StatusBadRequest,
-			Message: "参数错误",
-		})
-		return err
-	}
-
-	if err := t.DB.Create(model).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, Response{
-			Code:    http.StatusInternalServerError,
-			Message: "创建失败",
-		})
-		return err
+// invalidateRows 清除 rows（模型指针或切片指针）中每条记录的缓存（包括不存在记录的缓存），并使相关列表失效
+func (t *CRUDTool) invalidateRows(ctx context.Context, rows interface{}) {
+	if t.Cache == nil {
+		return
 	}
-
-	c.JSON(http.StatusCreated, Response{
-		Code:    http.StatusCreated,
-		Message: "创建成功",
-		Data:    model,
-	})
-	return nil
-}
-
-// UpdateByID 更新记录（带缓存失效）
-func (t *CRUDTool) UpdateByID(c *gin.Context, model interface{}) error {
-	start := time.Now()
-	var err error
-
-	defer func() {
-		t.LogOperation(c.Request.Context(), "update_by_id", model, time.Since(start), err, nil)
-	}()
-
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, Response{
-			Code:    http.StatusBadRequest,
-			Message: "无效的ID",
-		})
-		return err
+	sch, err := t.ParseSchema(rows)
+	if err != nil || sch.PrioritizedPrimaryField == nil {
+		return
 	}
 
-	// 先检查记录是否存在
-	if err := t.DB.First(model, id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, Response{
-				Code:    http.StatusNotFound,
-				Message: "记录不存在",
-			})
-		} else {
-			c.JSON(http.StatusInternalServerError, Response{
-				Code:    http.StatusInternalServerError,
-				Message: "查询失败",
-			})
+	var keys []string
+	tags := []string{t.modelTag(rows)}
+	add := func(v reflect.Value) {
+		if v.Kind() != reflect.Ptr {
+			v = v.Addr()
+		}
+		if id, zero := sch.PrioritizedPrimaryField.ValueOf(ctx, v.Elem()); !zero {
+			keys = append(keys, t.GenerateCacheKey(v.Interface(), id))
+			tags = append(tags, t.recordTag(rows, id))
 		}
-		return err
-	}
-
-	if err := c.ShouldBindJSON(model); err != nil {
-		c.JSON(http.StatusBadRequest, Response{
-			Code:    http.StatusBadRequest,
-			Message: "参数错误",
-		})
-		return err
 	}
 
-	if err := t.DB.Save(model).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, Response{
-			Code:    http.StatusInternalServerError,
-			Message: "更新失败",
-		})
-		return err
+	value := reflect.Indirect(reflect.ValueOf(rows))
+	switch value.Kind() {
+	case reflect.Struct:
+		if value.CanAddr() {
+			add(value)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if elem := value.Index(i); elem.Kind() == reflect.Ptr || elem.CanAddr() {
+				add(elem)
+			}
+		}
 	}
-
-	// 清除缓存
-	cacheKey := t.GenerateCacheKey(model, id)
-	t.DeleteFromCache(c.Request.Context(), cacheKey)
-
-	c.JSON(http.StatusOK, Response{
-		Code:    http.StatusOK,
-		Message: "更新成功",
-		Data:    model,
+	t.afterCommit(ctx, func(ctx context.Context) {
+		t.deleteCacheKeys(ctx, keys...)
 	})
-	return nil
+	t.bumpTags(ctx, tags...)
 }
 
-// SoftDeleteByID 软删除
-func (t *CRUDTool) SoftDeleteByID(c *gin.Context, model interface{}) error {
-	start := time.Now()
-	var err error
-
-	defer func() {
-		t.LogOperation(c.Request.Context(), "soft_delete", model, time.Since(start), err, nil)
-	}()
-
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, Response{
-			Code:    http.StatusBadRequest,
-			Message: "无效的ID",
-		})
-		return err
-	}
-
-	result := t.DB.Delete(model, id)
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, Response{
-			Code:    http.StatusInternalServerError,
-			Message: "删除失败",
-		})
-		return result.Error
-	}
-
-	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, Response{
-			Code:    http.StatusNotFound,
-			Message: "记录不存在",
-		})
-		return gorm.ErrRecordNotFound
+// cacheTTL 返回加上随机抖动后的 TTL
+func (t *CRUDTool) cacheTTL() time.Duration {
+	if t.CacheTTLJitter <= 0 {
+		return CacheTTL
 	}
-
-	// 清除缓存
-	cacheKey := t.GenerateCacheKey(model, id)
-	t.DeleteFromCache(c.Request.Context(), cacheKey)
-
-	c.JSON(http.StatusOK, Response{
-		Code:    http.StatusOK,
-		Message: "删除成功",
-	})
-	return nil
+	factor := 1 + t.CacheTTLJitter*(2*rand.Float64()-1)
+	return time.Duration(float64(CacheTTL) * factor)
 }
 
-// HardDeleteByID 硬删除
-func (t *CRUDTool) HardDeleteByID(c *gin.Context, model interface{}) error {
-	start := time.Now()
-	var err error
-
-	defer func() {
-		t.LogOperation(c.Request.Context(), "hard_delete", model, time.Since(start), err, nil)
-	}()
-
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, Response{
-			Code:    http.StatusBadRequest,
-			Message: "无效的ID",
-		})
-		return err
+// refreshEarly 按 XFetch 算法判断是否提前刷新缓存：
+// 加载耗时 * beta * -ln(rand) >= 剩余 TTL 时视为未命中
+func (t *CRUDTool) refreshEarly(ctx context.Context, key string, model interface{}) bool {
+	if t.CacheEarlyRefreshBeta <= 0 || t.Cache == nil {
+		return false
 	}
-
-	result := t.DB.Unscoped().Delete(model, id)
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, Response{
-			Code:    http.StatusInternalServerError,
-			Message: "删除失败",
-		})
-		return result.Error
+	v, ok := t.loadDurations.Load(fmt.Sprintf("%T", model))
+	if !ok {
+		return false
 	}
-
-	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, Response{
-			Code:    http.StatusNotFound,
-			Message: "记录不存在",
-		})
-		return gorm.ErrRecordNotFound
+	ttl, err := t.cacheKeyTTL(ctx, key)
+	if err != nil || ttl < 0 {
+		return false
 	}
-
-	// 清除缓存
-	cacheKey := t.GenerateCacheKey(model, id)
-	t.DeleteFromCache(c.Request.Context(), cacheKey)
-
-	c.JSON(http.StatusOK, Response{
-		Code:    http.StatusOK,
-		Message: "永久删除成功",
-	})
-	return nil
+	delta := float64(v.(time.Duration))
+	return delta*t.CacheEarlyRefreshBeta*-math.Log(rand.Float64()) >= float64(ttl)
 }
 
-// RestoreSoftDelete 恢复软删除的记录
-func (t *CRUDTool) RestoreSoftDelete(c *gin.Context, model interface{}) error {
-	start := time.Now()
-	var err error
-
-	defer func() {
-		t.LogOperation(c.Request.Context(), "restore", model, time.Since(start), err, nil)
-	}()
-
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, Response{
-			Code:    http.StatusBadRequest,
-			Message: "无效的ID",
-		})
-		return err
-	}
-
-	result := t.DB.Unscoped().Model(model).Where("id = ?", id).Update("deleted_at", nil)
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, Response{
-			Code:    http.StatusInternalServerError,
-			Message: "恢复失败",
-		})
-		return result.Error
-	}
-
-	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, Response{
-			Code:    http.StatusNotFound,
-			Message: "记录不存在",
-		})
-		return gorm.ErrRecordNotFound
+func (t *CRUDTool) DeleteFromCache(ctx context.Context, key string) error {
+	if t.Cache == nil {
+		return nil
 	}
 
-	c.JSON(http.StatusOK, Response{
-		Code:    http.StatusOK,
-		Message: "恢复成功",
-	})
-	return nil
-}
-
-// 批量操作（区分软删除和硬删除）
-func (t *CRUDTool) BatchOperation(c *gin.Context, models interface{}, operation string) error {
-	start := time.Now()
 	var err error
-
-	defer func() {
-		t.LogOperation(c.Request.Context(), "batch_"+operation, models, time.Since(start), err, nil)
-	}()
-
-	if err := c.ShouldBindJSON(models); err != nil {
-		c.JSON(http.StatusBadRequest, Response{
-			Code:    http.StatusBadRequest,
-			Message: "参数错误",
-		})
-		return err
-	}
-
-	var result *gorm.DB
-	switch operation {
-	case "create":
-		result = t.DB.Create(models)
-	case "update":
-		result = t.DB.Save(models)
-	case "soft_delete":
-		result = t.DB.Delete(models)
-	case "hard_delete":
-		result = t.DB.Unscoped().Delete(models)
-	default:
-		c.JSON(http.StatusBadRequest, Response{
-			Code:    http.StatusBadRequest,
-			Message: "不支持的批量操作",
-		})
-		return fmt.Errorf("unsupported batch operation")
-	}
-
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, Response{
-			Code:    http.StatusInternalServerError,
-			Message: "批量操作失败",
-		})
-		return result.Error
-	}
-
-	c.JSON(http.StatusOK, Response{
-		Code:    http.StatusOK,
-		Message: "批量操作成功",
-		Data:    result.RowsAffected,
+	t.afterCommit(ctx, func(ctx context.Context) {
+		err = t.deleteCacheKeys(ctx, key)
 	})
-	return nil
+	return err
 }
 
-// GetMetrics 获取性能指标
+// GetMetrics 获取性能指标（JSON），Prometheus 格式见 PrometheusMetrics
 func (t *CRUDTool) GetMetrics(c *gin.Context) {
 	metrics := gin.H{}
 
@@ -1048,8 +382,11 @@ func (t *CRUDTool) GetMetrics(c *gin.Context) {
 		metrics["database"] = "无法获取数据库统计信息: " + err.Error()
 	}
 
-	// 获取 Redis 统计信息
-	metrics["redis"] = t.getRedisStats(c.Request.Context())
+	// 获取缓存统计信息
+	metrics["cache"] = t.getCacheStats(c.Request.Context())
+	metrics["cache_models"] = t.CacheMetrics()
+	metrics["cache_breaker"] = t.CacheBreakerStats()
+	metrics["slowest_sql"] = t.SlowestQueries(10)
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    http.StatusOK,
@@ -1058,30 +395,19 @@ func (t *CRUDTool) GetMetrics(c *gin.Context) {
 	})
 }
 
-// getRedisStats 获取 Redis 统计信息
-func (t *CRUDTool) getRedisStats(ctx context.Context) interface{} {
-	if t.RedisClient == nil {
-		return "Redis 未配置"
+// getCacheStats 获取缓存统计信息
+func (t *CRUDTool) getCacheStats(ctx context.Context) interface{} {
+	if t.Cache == nil {
+		return "缓存未配置"
 	}
 
-	// 获取 Redis 信息
-	info, err := t.RedisClient.Info(ctx).Result()
-	if err != nil {
-		return "无法获取 Redis 信息: " + err.Error()
+	stats, ok := t.Cache.(CacheStats)
+	if !ok {
+		return fmt.Sprintf("%T 不支持统计信息", t.Cache)
 	}
-
-	// 解析 Redis 信息为更结构化的格式
-	redisStats := make(map[string]string)
-	lines := strings.Split(info, "\r\n")
-	for _, line := range lines {
-		if strings.HasPrefix(line, "#") || line == "" {
-			continue
-		}
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) == 2 {
-			redisStats[parts[0]] = parts[1]
-		}
+	data, err := cacheCall(t, ctx, false, stats.Stats)
+	if err != nil {
+		return "无法获取缓存统计信息: " + err.Error()
 	}
-
-	return redisStats
+	return data
 }
@@ -0,0 +1,265 @@
+// gormtool\cursor.go
+package gormtool
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// 游标（keyset）分页
+// 按排序字段（最后补充 id 保证唯一）的值定位下一页，不使用 OFFSET，
+// 翻页期间插入新记录不会导致重复或遗漏，默认不执行 COUNT(*)。
+// 排序字段应为非空列，NULL 值无法参与比较。
+
+// CursorRequest 游标分页参数
+type CursorRequest struct {
+	Cursor    string // 上一次返回的 next_cursor 或 prev_cursor，为空表示第一页
+	PageSize  int
+	WithTotal bool // 是否额外统计总数
+}
+
+// cursorToken 游标内容，编码为 base64 JSON，对客户端不透明
+type cursorToken struct {
+	Sort   string            `json:"s"`           // 排序签名，排序变化后旧游标失效
+	Values []json.RawMessage `json:"v"`           // 排序字段的值
+	Prev   bool              `json:"p,omitempty"` // 向前翻页
+}
+
+// keysetColumn 参与游标比较的排序列
+type keysetColumn struct {
+	field *schema.Field
+	col   clause.Column
+	desc  bool
+}
+
+// keysetColumns 解析排序字段，没有包含主键时追加 id 升序
+func (r *fieldResolver) keysetColumns(sorts []SortCondition) ([]keysetColumn, string, error) {
+	keys := make([]keysetColumn, 0, len(sorts)+1)
+	hasPrimary := false
+	for _, sort := range sorts {
+		order, err := r.buildSort(sort)
+		if err != nil {
+			return nil, "", err
+		}
+		field, _ := r.resolve(sort.Field)
+		hasPrimary = hasPrimary || field.PrimaryKey
+		keys = append(keys, keysetColumn{field: field, col: order.Column, desc: order.Desc})
+	}
+
+	if !hasPrimary {
+		field := r.schema.PrioritizedPrimaryField
+		if field == nil {
+			return nil, "", invalidQuery("游标分页需要模型有主键")
+		}
+		keys = append(keys, keysetColumn{
+			field: field,
+			col:   clause.Column{Table: clause.CurrentTable, Name: field.DBName},
+		})
+	}
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k.field.DBName
+		if k.desc {
+			parts[i] += ":desc"
+		}
+	}
+	return keys, strings.Join(parts, ","), nil
+}
+
+// keysetExpr 生成 (a > ?) OR (a = ? AND b > ?) ... 形式的定位条件
+func keysetExpr(keys []keysetColumn, values []interface{}, prev bool) clause.Expression {
+	ors := make([]clause.Expression, 0, len(keys))
+	for i, k := range keys {
+		ands := make([]clause.Expression, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, clause.Expr{SQL: "? = ?", Vars: []interface{}{keys[j].col, values[j]}})
+		}
+		op := ">"
+		if k.desc != prev {
+			op = "<"
+		}
+		ands = append(ands, clause.Expr{SQL: "? " + op + " ?", Vars: []interface{}{k.col, values[i]}})
+		ors = append(ors, groupExpr{logic: "AND", exprs: ands})
+	}
+	return groupExpr{logic: "OR", exprs: ors}
+}
+
+// encodeCursor 用记录的排序字段值生成游标
+func encodeCursor(ctx context.Context, keys []keysetColumn, signature string, row reflect.Value, prev bool) (string, error) {
+	token := cursorToken{Sort: signature, Prev: prev, Values: make([]json.RawMessage, len(keys))}
+	for i, k := range keys {
+		value, _ := k.field.ValueOf(ctx, reflect.Indirect(row))
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		token.Values[i] = raw
+	}
+	data, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor 解析游标并把值还原为字段类型
+func decodeCursor(cursor string, keys []keysetColumn, signature string) ([]interface{}, bool, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, false, invalidQuery("无效的游标")
+	}
+	var token cursorToken
+	if err := json.Unmarshal(data, &token); err != nil || len(token.Values) != len(keys) {
+		return nil, false, invalidQuery("无效的游标")
+	}
+	if token.Sort != signature {
+		return nil, false, invalidQuery("游标与当前排序不匹配")
+	}
+
+	values := make([]interface{}, len(keys))
+	for i, k := range keys {
+		v := reflect.New(k.field.FieldType)
+		if err := json.Unmarshal(token.Values[i], v.Interface()); err != nil {
+			return nil, false, invalidQuery("无效的游标")
+		}
+		values[i] = v.Elem().Interface()
+	}
+	return values, token.Prev, nil
+}
+
+// FindCursorPage 使用查询构建器游标分页查询，models 为切片指针
+func (t *CRUDTool) FindCursorPage(ctx context.Context, models interface{}, qb *QueryBuilder, req CursorRequest) (p *Pagination, err error) {
+	start := time.Now()
+	ctx = t.startOperation(ctx, "get_by_cursor", models)
+	_, pageSize := normalizePage(1, req.PageSize)
+	cached := false
+
+	defer func() {
+		t.logService(ctx, "get_by_cursor", models, start, err, map[string]interface{}{
+			"pagesize":   pageSize,
+			"with_total": req.WithTotal,
+			"cached":     cached,
+		})
+	}()
+
+	params := map[string]interface{}{"qb": qb, "cursor": req.Cursor, "pagesize": pageSize, "with_total": req.WithTotal}
+	p, cached, err = t.cachedList(ctx, "cursor", models, params, qb.preloadNames(), func() (*Pagination, error) {
+		return t.loadCursorPage(ctx, models, qb, req.Cursor, pageSize, req.WithTotal)
+	})
+	if err != nil {
+		err = wrapDBError("get_by_cursor", "查询失败", err)
+		return nil, err
+	}
+	return p, nil
+}
+
+// loadCursorPage 执行游标分页查询
+func (t *CRUDTool) loadCursorPage(ctx context.Context, models interface{}, qb *QueryBuilder, cursor string, pageSize int, withTotal bool) (p *Pagination, err error) {
+	r, err := t.newFieldResolver(models)
+	if err != nil {
+		err = wrapDBError("get_by_cursor", "查询失败", err)
+		return nil, err
+	}
+
+	// 相关度不是列，无法作为游标
+	if qb != nil && qb.Search != "" {
+		err = invalidQuery("游标分页不支持全文搜索")
+		return nil, err
+	}
+
+	var sorts []SortCondition
+	base := &QueryBuilder{}
+	if qb != nil {
+		sorts = qb.Sorts
+		base = &QueryBuilder{
+			Conditions:      qb.Conditions,
+			Where:           qb.Where,
+			Preloads:        qb.Preloads,
+			SelectRelations: qb.SelectRelations,
+		}
+	}
+	keys, signature, err := r.keysetColumns(sorts)
+	if err != nil {
+		return nil, err
+	}
+
+	// 稀疏字段需要包含排序字段，否则无法生成游标
+	if qb != nil && len(qb.Select) > 0 {
+		base.Select = append([]string(nil), qb.Select...)
+		for _, k := range keys {
+			base.Select = append(base.Select, k.field.DBName)
+		}
+	}
+
+	// 排序由游标控制，这里只构建条件和预加载
+	db, err := t.BuildQuery(t.DB.WithContext(ctx), models, base)
+	if err != nil {
+		err = wrapDBError("get_by_cursor", "查询失败", err)
+		return nil, err
+	}
+
+	p = &Pagination{PageSize: pageSize, Total: -1}
+	if withTotal {
+		var total int64
+		if err = db.Model(models).Count(&total).Error; err != nil {
+			err = wrapDBError("get_by_cursor", "查询失败", err)
+			return nil, err
+		}
+		p.Total = int(total)
+	}
+
+	prev := false
+	if cursor != "" {
+		var values []interface{}
+		values, prev, err = decodeCursor(cursor, keys, signature)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where(keysetExpr(keys, values, prev))
+	}
+
+	// 向前翻页时反转排序，查询后再把结果反转回来
+	for _, k := range keys {
+		db = db.Order(clause.OrderByColumn{Column: k.col, Desc: k.desc != prev})
+	}
+
+	// 多取一条判断是否还有更多
+	if err = db.Limit(pageSize + 1).Find(models).Error; err != nil {
+		err = wrapDBError("get_by_cursor", "查询失败", err)
+		return nil, err
+	}
+
+	rows := reflect.ValueOf(models).Elem()
+	hasMore := rows.Len() > pageSize
+	if hasMore {
+		rows.Set(rows.Slice(0, pageSize))
+	}
+	if prev {
+		swap := reflect.Swapper(rows.Interface())
+		for i, j := 0, rows.Len()-1; i < j; i, j = i+1, j-1 {
+			swap(i, j)
+		}
+	}
+
+	if n := rows.Len(); n > 0 {
+		if (prev && hasMore) || (!prev && cursor != "") {
+			if p.PrevCursor, err = encodeCursor(ctx, keys, signature, rows.Index(0), true); err != nil {
+				return nil, err
+			}
+		}
+		if (!prev && hasMore) || prev {
+			if p.NextCursor, err = encodeCursor(ctx, keys, signature, rows.Index(n-1), false); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return p, nil
+}
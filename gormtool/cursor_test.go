@@ -0,0 +1,121 @@
+// gormtool\cursor_test.go
+package gormtool
+
+import (
+	"context"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// cursorIDs 返回记录的 ID
+func cursorIDs(items []testItem) []uint {
+	ids := make([]uint, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	return ids
+}
+
+// 排序字段有重复值时按 id 补充排序，向后翻页不重复不遗漏，向前翻页回到相同的记录
+func TestFindCursorPage(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	items := env.seed(t, "a", "b", "c", "d", "e", "f", "g")
+	if err := env.tool.DB.Model(&testItem{}).Where("1 = 1").Update("score", gorm.Expr("id % 3")).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	qb := &QueryBuilder{Sorts: []SortCondition{{Field: "score", Direction: "desc"}}}
+	var pages [][]uint
+	seen := make(map[uint]bool)
+	cursor := ""
+	for {
+		var page []testItem
+		p, err := env.tool.FindCursorPage(ctx, &page, qb, CursorRequest{Cursor: cursor, PageSize: 3})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Total != -1 {
+			t.Fatalf("未请求总数时 Total = %d, want -1", p.Total)
+		}
+		if (cursor == "") != (p.PrevCursor == "") {
+			t.Fatalf("第 %d 页 PrevCursor = %q", len(pages)+1, p.PrevCursor)
+		}
+		for _, item := range page {
+			if seen[item.ID] {
+				t.Fatalf("记录 %d 重复出现", item.ID)
+			}
+			seen[item.ID] = true
+		}
+		pages = append(pages, cursorIDs(page))
+		if p.NextCursor == "" {
+			break
+		}
+		cursor = p.NextCursor
+	}
+	if len(seen) != len(items) || len(pages) != 3 {
+		t.Fatalf("pages = %v, want %d 条记录分 3 页", pages, len(items))
+	}
+
+	// 从最后一页向前翻页
+	var last []testItem
+	p, err := env.tool.FindCursorPage(ctx, &last, qb, CursorRequest{Cursor: cursor, PageSize: 3, WithTotal: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Total != len(items) {
+		t.Fatalf("Total = %d, want %d", p.Total, len(items))
+	}
+	var prev []testItem
+	if p, err = env.tool.FindCursorPage(ctx, &prev, qb, CursorRequest{Cursor: p.PrevCursor, PageSize: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cursorIDs(prev), pages[1]; !equalIDs(got, want) {
+		t.Fatalf("向前翻页 = %v, want %v", got, want)
+	}
+	if p.PrevCursor == "" || p.NextCursor == "" {
+		t.Fatalf("中间页游标 = %+v", p)
+	}
+}
+
+// 排序变化或内容无效的游标返回参数错误
+func TestFindCursorPageInvalidCursor(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	env.seed(t, "a", "b", "c")
+
+	var page []testItem
+	qb := &QueryBuilder{Sorts: []SortCondition{{Field: "score", Direction: "asc"}}}
+	p, err := env.tool.FindCursorPage(ctx, &page, qb, CursorRequest{PageSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resorted := &QueryBuilder{Sorts: []SortCondition{{Field: "name", Direction: "asc"}}}
+	for name, req := range map[string]struct {
+		qb     *QueryBuilder
+		cursor string
+	}{
+		"排序变化":     {resorted, p.NextCursor},
+		"非 base64": {qb, "!!!"},
+		"非 JSON":   {qb, "bm90LWpzb24"},
+	} {
+		_, err := env.tool.FindCursorPage(ctx, &page, req.qb, CursorRequest{Cursor: req.cursor, PageSize: 1})
+		if KindOf(err) != ErrKindInvalidArgument {
+			t.Errorf("%s: err = %v, want invalid argument", name, err)
+		}
+	}
+}
+
+func equalIDs(a, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+// gormtool\errors.go
+package gormtool
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"gorm.io/gorm"
+)
+
+// ErrorKind 错误类型，同时作为日志中的 error_type 字段
+type ErrorKind string
+
+const (
+	ErrKindInvalidID       ErrorKind = "invalid_id"
+	ErrKindBind            ErrorKind = "bind_error"
+	ErrKindInvalidArgument ErrorKind = "invalid_argument"
+	ErrKindNotFound        ErrorKind = "not_found"
+	ErrKindDB              ErrorKind = "db_error"
+	ErrKindInternal        ErrorKind = "internal_error" // panic 等内部错误，见 RecoveryMiddleware
+)
+
+// Error 服务层统一错误
+// Kind 决定 HTTP 状态码，Message 为返回给客户端的提示信息，Details 为返回给客户端的错误详情，Err 为原始错误
+type Error struct {
+	Kind    ErrorKind
+	Op      string
+	Message string
+	Details interface{}
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Op, e.Kind, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Op, e.Kind)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Status 返回错误对应的 HTTP 状态码
+func (e *Error) Status() int {
+	switch e.Kind {
+	case ErrKindInvalidID, ErrKindBind, ErrKindInvalidArgument:
+		return http.StatusBadRequest
+	case ErrKindNotFound:
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// NewError 创建服务层错误
+func NewError(kind ErrorKind, op, message string, err error) *Error {
+	return &Error{Kind: kind, Op: op, Message: message, Err: err}
+}
+
+// KindOf 返回错误类型，非 *Error 视为数据库错误
+func KindOf(err error) ErrorKind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrKindNotFound
+	}
+	return ErrKindDB
+}
+
+// IsNotFound 判断是否为记录不存在
+func IsNotFound(err error) bool {
+	return err != nil && KindOf(err) == ErrKindNotFound
+}
+
+// wrapDBError 包装数据库错误，ErrRecordNotFound 转换为 ErrKindNotFound
+func wrapDBError(op, message string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return NewError(ErrKindNotFound, op, "记录不存在", err)
+	}
+	return NewError(ErrKindDB, op, message, err)
+}
@@ -0,0 +1,292 @@
+// gormtool\fields.go
+package gormtool
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// 稀疏字段集
+// SQL 只查询请求的列（自动补充主键和预加载所需的外键），
+// 响应 JSON 只包含请求的字段和预加载的关联，见 ProjectFields
+//
+//	?fields=id,name&fields[Tags]=id,name&include=Tags
+//
+// fields[关联] 中的关联即使没有出现在 include 中也会被预加载
+
+var relationFieldsPattern = regexp.MustCompile(`^fields\[([^\[\]]+)\]$`)
+
+// parseFieldsParams 解析 fields 和 fields[关联] 参数
+func parseFieldsParams(values url.Values) ([]string, map[string][]string) {
+	var fields []string
+	var relations map[string][]string
+	for key, vals := range values {
+		if key == "fields" {
+			for _, v := range vals {
+				fields = append(fields, splitList(v)...)
+			}
+			continue
+		}
+		if m := relationFieldsPattern.FindStringSubmatch(key); m != nil {
+			if relations == nil {
+				relations = map[string][]string{}
+			}
+			for _, v := range vals {
+				relations[m[1]] = append(relations[m[1]], splitList(v)...)
+			}
+		}
+	}
+	return fields, relations
+}
+
+// hasSelection 是否指定了稀疏字段
+func (qb *QueryBuilder) hasSelection() bool {
+	return qb != nil && (len(qb.Select) > 0 || len(qb.SelectRelations) > 0)
+}
+
+// selectColumns 解析需要查询的列，补充主键和 required 中的列，结果去重
+func (r *fieldResolver) selectColumns(names []string, required []string) ([]string, error) {
+	seen := map[string]bool{}
+	columns := make([]string, 0, len(names)+len(required)+1)
+	add := func(column string) {
+		if column != "" && !seen[column] {
+			seen[column] = true
+			columns = append(columns, column)
+		}
+	}
+
+	for _, field := range r.schema.PrimaryFields {
+		add(field.DBName)
+	}
+	for _, name := range names {
+		field, err := r.resolve(name)
+		if err != nil {
+			return nil, err
+		}
+		add(field.DBName)
+	}
+	for _, column := range required {
+		add(column)
+	}
+	return columns, nil
+}
+
+// relationKeys 返回加载关联时 sch 一侧必须查询的键列
+func relationKeys(rel *schema.Relationship, sch *schema.Schema) []string {
+	var keys []string
+	for _, ref := range rel.References {
+		if ref.PrimaryKey != nil && ref.PrimaryKey.Schema == sch {
+			keys = append(keys, ref.PrimaryKey.DBName)
+		}
+		if ref.ForeignKey != nil && ref.ForeignKey.Schema == sch {
+			keys = append(keys, ref.ForeignKey.DBName)
+		}
+	}
+	return keys
+}
+
+// preloadNames 返回需要预加载的关联：Preloads 加上指定了字段的关联，保持顺序并去重
+func (qb *QueryBuilder) preloadNames() []string {
+	if qb == nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	var names []string
+	for _, name := range qb.Preloads {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	extra := make([]string, 0, len(qb.SelectRelations))
+	for name := range qb.SelectRelations {
+		if !seen[name] {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	return append(names, extra...)
+}
+
+// parentPath 返回预加载路径的上一级，第一层关联返回空字符串
+func parentPath(preload string) string {
+	if i := strings.LastIndex(preload, "."); i >= 0 {
+		return preload[:i]
+	}
+	return ""
+}
+
+// applySelectAndPreloads 应用稀疏字段和预加载
+// 每一级只查询请求的列，并补充主键以及加载上下级关联所需的键列
+func (t *CRUDTool) applySelectAndPreloads(db *gorm.DB, r *fieldResolver, qb *QueryBuilder) (*gorm.DB, error) {
+	preloads := qb.preloadNames()
+	relations := make(map[string]*schema.Relationship, len(preloads))
+	for _, preload := range preloads {
+		rel, err := r.checkPreload(preload)
+		if err != nil {
+			return nil, err
+		}
+		relations[preload] = rel
+	}
+
+	// 路径 -> 该级必须查询的键列，"" 表示主模型
+	required := map[string][]string{}
+	for _, preload := range preloads {
+		rel := relations[preload]
+		parent := parentPath(preload)
+		parentSchema := r.schema
+		if parent != "" {
+			if parentRel, err := r.checkPreload(parent); err == nil {
+				parentSchema = parentRel.FieldSchema
+			}
+		}
+		required[parent] = append(required[parent], relationKeys(rel, parentSchema)...)
+		required[preload] = append(required[preload], relationKeys(rel, rel.FieldSchema)...)
+	}
+
+	if len(qb.Select) > 0 {
+		columns, err := r.selectColumns(qb.Select, required[""])
+		if err != nil {
+			return nil, err
+		}
+		db = db.Select(columns)
+	}
+
+	for _, preload := range preloads {
+		fields := qb.SelectRelations[preload]
+		if len(fields) == 0 {
+			db = db.Preload(preload)
+			continue
+		}
+		columns, err := t.resolverFor(relations[preload].FieldSchema).selectColumns(fields, required[preload])
+		if err != nil {
+			return nil, err
+		}
+		db = db.Preload(preload, func(tx *gorm.DB) *gorm.DB {
+			return tx.Select(columns)
+		})
+	}
+	return db, nil
+}
+
+// jsonName 返回字段序列化后的 JSON 键名
+func jsonName(field *schema.Field) string {
+	if tag, ok := field.StructField.Tag.Lookup("json"); ok {
+		if name := strings.Split(tag, ",")[0]; name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// projection JSON 投影：keys 为保留的键（nil 表示全部保留），relations 为关联键的子投影
+type projection struct {
+	keys      map[string]bool
+	relations map[string]*projection
+}
+
+// newProjection 按字段列表创建投影节点，总是保留主键
+func newProjection(r *fieldResolver, fields []string) (*projection, error) {
+	p := &projection{relations: map[string]*projection{}}
+	if len(fields) == 0 {
+		return p, nil
+	}
+	p.keys = map[string]bool{}
+	for _, field := range r.schema.PrimaryFields {
+		p.keys[jsonName(field)] = true
+	}
+	for _, name := range fields {
+		field, err := r.resolve(name)
+		if err != nil {
+			return nil, err
+		}
+		p.keys[jsonName(field)] = true
+	}
+	return p, nil
+}
+
+// buildProjection 根据 QueryBuilder 的稀疏字段和预加载构建投影树
+func (t *CRUDTool) buildProjection(r *fieldResolver, qb *QueryBuilder) (*projection, error) {
+	root, err := newProjection(r, qb.Select)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, preload := range qb.preloadNames() {
+		node, sch, path := root, r.schema, ""
+		for _, name := range strings.Split(preload, ".") {
+			rel, ok := sch.Relationships.Relations[name]
+			if !ok {
+				return nil, invalidQuery("无效的预加载关联: %s", preload)
+			}
+			if path != "" {
+				path += "."
+			}
+			path += name
+
+			key := jsonName(rel.Field)
+			if node.keys != nil {
+				node.keys[key] = true
+			}
+			child, ok := node.relations[key]
+			if !ok {
+				if child, err = newProjection(t.resolverFor(rel.FieldSchema), qb.SelectRelations[path]); err != nil {
+					return nil, err
+				}
+				node.relations[key] = child
+			}
+			node, sch = child, rel.FieldSchema
+		}
+	}
+	return root, nil
+}
+
+// apply 原地裁剪 json.Unmarshal 得到的通用结构
+func (p *projection) apply(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		for i, item := range v {
+			v[i] = p.apply(item)
+		}
+	case map[string]interface{}:
+		for key, item := range v {
+			if p.keys != nil && !p.keys[key] {
+				delete(v, key)
+				continue
+			}
+			if sub, ok := p.relations[key]; ok {
+				v[key] = sub.apply(item)
+			}
+		}
+	}
+	return value
+}
+
+// ProjectFields 把模型（或模型切片）转换为只包含 qb 中稀疏字段和预加载关联的 JSON 结构，
+// 没有指定稀疏字段时原样返回
+func (t *CRUDTool) ProjectFields(data interface{}, qb *QueryBuilder) (interface{}, error) {
+	if !qb.hasSelection() {
+		return data, nil
+	}
+
+	r, err := t.newFieldResolver(data)
+	if err != nil {
+		return nil, err
+	}
+	p, err := t.buildProjection(r, qb)
+	if err != nil {
+		return nil, err
+	}
+
+	generic, err := toGeneric(data)
+	if err != nil {
+		return nil, err
+	}
+	return p.apply(generic), nil
+}
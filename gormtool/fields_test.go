@@ -0,0 +1,198 @@
+// gormtool\fields_test.go
+package gormtool
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 多级关联的测试模型：作者 -> 书 -> 章节
+type fieldAuthor struct {
+	ID    uint
+	Name  string
+	Bio   string
+	Books []fieldBook `gorm:"foreignKey:AuthorID"`
+}
+
+type fieldBook struct {
+	ID       uint
+	AuthorID uint
+	Title    string
+	Summary  string
+	Chapters []fieldChapter `gorm:"foreignKey:BookID"`
+}
+
+type fieldChapter struct {
+	ID     uint
+	BookID uint
+	Title  string
+	Pages  int
+}
+
+// objectKeys 返回对象的键，按字母排序后用逗号连接
+func objectKeys(v interface{}) string {
+	m, _ := v.(map[string]interface{})
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+// 列表接口的 fields 参数：只返回请求的字段和主键，未知字段、白名单之外的字段返回 400，别名按策略解析
+func TestFieldsParams(t *testing.T) {
+	env := newTestEnv(t)
+	env.seed(t, "a", "b")
+	r := newTestRouter()
+	r.GET("/items", func(c *gin.Context) {
+		var items []testItem
+		env.tool.GetByQueryBuilder(c, &items, nil)
+	})
+
+	w, resp := doRequest(t, r, http.MethodGet, "/items?fields=name", "")
+	items, _ := resp.Data.([]interface{})
+	if w.Code != http.StatusOK || len(items) != 2 {
+		t.Fatalf("fields=name = %d %+v", w.Code, resp)
+	}
+	if keys := objectKeys(items[0]); keys != "ID,Name" {
+		t.Fatalf("fields=name 返回的键 = %s, want ID,Name", keys)
+	}
+
+	// 列名和驼峰都可以，重复字段去重
+	if _, resp = doRequest(t, r, http.MethodGet, "/items?fields=Score,created_at,score", ""); objectKeys(resp.Data.([]interface{})[0]) != "CreatedAt,ID,Score" {
+		t.Fatalf("fields=Score,created_at = %+v", resp.Data)
+	}
+
+	for _, target := range []string{"/items?fields=nope", "/items?fields=name,nope", "/items?fields[Owner]=id", "/items?fields=name%3Bdrop"} {
+		if w, resp := doRequest(t, r, http.MethodGet, target, ""); w.Code != http.StatusBadRequest || resp.ErrorCode != ErrKindInvalidArgument {
+			t.Errorf("%s = %d %+v, want 400", target, w.Code, resp)
+		}
+	}
+
+	env.tool.SetFieldPolicy(&testItem{}, FieldPolicy{
+		Allowed: []string{"name"},
+		Aliases: map[string]string{"title": "name", "points": "score"},
+	})
+	if w, resp = doRequest(t, r, http.MethodGet, "/items?fields=title", ""); w.Code != http.StatusOK || objectKeys(resp.Data.([]interface{})[0]) != "ID,Name" {
+		t.Fatalf("别名 fields=title = %d %+v", w.Code, resp)
+	}
+	for _, target := range []string{"/items?fields=score", "/items?fields=points"} {
+		if w, resp := doRequest(t, r, http.MethodGet, target, ""); w.Code != http.StatusBadRequest || resp.ErrorCode != ErrKindInvalidArgument {
+			t.Errorf("白名单之外 %s = %d %+v, want 400", target, w.Code, resp)
+		}
+	}
+}
+
+// 多级预加载的稀疏字段：每一级只查询请求的列，自动补充主键和外键，JSON 中只保留请求的字段
+func TestFieldsNestedPreloads(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	if err := env.tool.DB.AutoMigrate(&fieldAuthor{}, &fieldBook{}, &fieldChapter{}); err != nil {
+		t.Fatal(err)
+	}
+	author := fieldAuthor{Name: "a", Bio: "bio", Books: []fieldBook{
+		{Title: "b1", Summary: "s1", Chapters: []fieldChapter{{Title: "c1", Pages: 10}, {Title: "c2", Pages: 20}}},
+		{Title: "b2", Summary: "s2"},
+	}}
+	if err := env.tool.DB.Create(&author).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	qb := &QueryBuilder{
+		Select: []string{"name"},
+		SelectRelations: map[string][]string{
+			"Books":          {"title"},
+			"Books.Chapters": {"pages"},
+		},
+	}
+	var authors []fieldAuthor
+	if _, err := env.tool.FindPage(ctx, &authors, qb, 1, 10); err != nil {
+		t.Fatal(err)
+	}
+	if len(authors) != 1 || len(authors[0].Books) != 2 {
+		t.Fatalf("authors = %+v", authors)
+	}
+	got := authors[0]
+	if got.Name != "a" || got.Bio != "" {
+		t.Fatalf("主模型只应查询 name: %+v", got)
+	}
+	book := got.Books[0]
+	if book.Title != "b1" || book.Summary != "" || len(book.Chapters) != 2 {
+		t.Fatalf("Books 只应查询 title（外键自动补充）: %+v", book)
+	}
+	if ch := book.Chapters[0]; ch.Pages != 10 || ch.Title != "" {
+		t.Fatalf("Chapters 只应查询 pages: %+v", ch)
+	}
+
+	data, err := env.tool.ProjectFields(&authors, qb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := data.([]interface{})[0].(map[string]interface{})
+	if keys := objectKeys(row); keys != "Books,ID,Name" {
+		t.Fatalf("author 键 = %s", keys)
+	}
+	bookRow := row["Books"].([]interface{})[0].(map[string]interface{})
+	if keys := objectKeys(bookRow); keys != "Chapters,ID,Title" {
+		t.Fatalf("book 键 = %s", keys)
+	}
+	if keys := objectKeys(bookRow["Chapters"].([]interface{})[0]); keys != "ID,Pages" {
+		t.Fatalf("chapter 键 = %s", keys)
+	}
+
+	// 只指定下一级的字段时，中间一级返回全部字段
+	qb = &QueryBuilder{Select: []string{"name"}, SelectRelations: map[string][]string{"Books.Chapters": {"title"}}}
+	authors = nil
+	if _, err := env.tool.FindPage(ctx, &authors, qb, 1, 10); err != nil {
+		t.Fatal(err)
+	}
+	if book := authors[0].Books[0]; book.Summary != "s1" || book.Chapters[1].Title != "c2" {
+		t.Fatalf("Books.Chapters 字段 = %+v", book)
+	}
+
+	for _, bad := range []map[string][]string{
+		{"Books": {"nope"}},
+		{"Books.Chapters": {"nope"}},
+		{"Books.Nope": {"id"}},
+		{"Nope": {"id"}},
+	} {
+		qb := &QueryBuilder{SelectRelations: bad}
+		var authors []fieldAuthor
+		if _, err := env.tool.FindPage(ctx, &authors, qb, 1, 10); KindOf(err) != ErrKindInvalidArgument {
+			t.Errorf("SelectRelations %v: err = %v, want invalid argument", bad, err)
+		}
+	}
+}
+
+// 稀疏字段与搜索高亮同时使用：投影后的每条结果仍然带 _highlight
+func TestFieldsWithHighlight(t *testing.T) {
+	env := newTestEnv(t)
+	env.seed(t, "red apple", "pear")
+	if err := env.tool.RegisterSearch(&testItem{}, "Name"); err != nil {
+		t.Fatal(err)
+	}
+	r := newTestRouter()
+	r.GET("/items", func(c *gin.Context) {
+		var items []testItem
+		env.tool.GetByQueryBuilder(c, &items, nil)
+	})
+
+	w, resp := doRequest(t, r, http.MethodGet, "/items?search=apple&fields=score", "")
+	items, _ := resp.Data.([]interface{})
+	if w.Code != http.StatusOK || len(items) != 1 {
+		t.Fatalf("search + fields = %d %+v", w.Code, resp)
+	}
+	row := items[0].(map[string]interface{})
+	if keys := objectKeys(row); keys != "ID,Score,"+HighlightKey {
+		t.Fatalf("返回的键 = %s", keys)
+	}
+	if hl, _ := row[HighlightKey].(map[string]interface{}); hl["Name"] != "red <mark>apple</mark>" {
+		t.Fatalf("%s = %v", HighlightKey, row[HighlightKey])
+	}
+}
@@ -0,0 +1,320 @@
+// gormtool\gorm_logger.go
+package gormtool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// GORM SQL 日志
+// SQLLogger 返回 gorm logger.Interface 的实现，SQL 日志通过 t.Logger 输出（带上请求 context 中的 request_id 等字段）：
+//
+//	gormlogger.Error  只记录执行失败的语句（记录不存在不算失败）
+//	gormlogger.Warn   另外记录超过 SlowQueryThreshold 的慢查询，带影响行数和调用位置
+//	gormlogger.Info   记录所有语句（Debug 级别）
+//
+// 不论级别如何，每条语句按归一化后的 SQL（参数值替换为 ?）统计次数和耗时，GetMetrics 中返回最慢的语句；
+// 设置 DisableSQLStats 后不统计。调用位置需要遍历调用栈，只在记录日志或刷新最大耗时时查找。
+
+// DefaultSlowQueryThreshold SlowQueryThreshold 未设置时的慢查询阈值
+const DefaultSlowQueryThreshold = 200 * time.Millisecond
+
+// maxSQLStats 最多统计的归一化语句数，超过后新语句不再统计
+const maxSQLStats = 1000
+
+// explainTimeout 执行 EXPLAIN QUERY PLAN 的超时时间
+const explainTimeout = time.Second
+
+// SQLStats 一条归一化语句的统计
+type SQLStats struct {
+	SQL       string  `json:"sql"`
+	Count     int64   `json:"count"`
+	SlowCount int64   `json:"slow_count"`
+	Errors    int64   `json:"errors"`
+	AvgMs     float64 `json:"avg_ms"`
+	MaxMs     float64 `json:"max_ms"`
+	LastRows  int64   `json:"last_rows"`
+	Caller    string  `json:"caller"` // 最慢一次的调用位置
+}
+
+type sqlCounter struct {
+	count, slow, errors int64
+	total, max          time.Duration
+	lastRows            int64
+	caller              string
+}
+
+// sqlStatsRegistry 按归一化 SQL 统计
+type sqlStatsRegistry struct {
+	mu    sync.Mutex
+	stats map[string]*sqlCounter
+}
+
+// caller 只在刷新最大耗时时调用
+func (r *sqlStatsRegistry) observe(sql string, elapsed time.Duration, rows int64, slow, failed bool, caller func() string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.stats[sql]
+	if !ok {
+		if r.stats == nil {
+			r.stats = make(map[string]*sqlCounter)
+		}
+		if len(r.stats) >= maxSQLStats {
+			return
+		}
+		c = &sqlCounter{}
+		r.stats[sql] = c
+	}
+	c.count++
+	c.total += elapsed
+	c.lastRows = rows
+	if slow {
+		c.slow++
+	}
+	if failed {
+		c.errors++
+	}
+	if elapsed >= c.max {
+		c.max = elapsed
+		c.caller = caller()
+	}
+}
+
+// SlowestQueries 返回最大耗时最长的 n 条归一化语句（n <= 0 时返回全部）
+func (t *CRUDTool) SlowestQueries(n int) []SQLStats {
+	r := &t.sqlStats
+	r.mu.Lock()
+	list := make([]SQLStats, 0, len(r.stats))
+	for sql, c := range r.stats {
+		list = append(list, SQLStats{
+			SQL:       sql,
+			Count:     c.count,
+			SlowCount: c.slow,
+			Errors:    c.errors,
+			AvgMs:     durationMs(c.total) / float64(c.count),
+			MaxMs:     durationMs(c.max),
+			LastRows:  c.lastRows,
+			Caller:    c.caller,
+		})
+	}
+	r.mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].MaxMs != list[j].MaxMs {
+			return list[i].MaxMs > list[j].MaxMs
+		}
+		return list[i].SQL < list[j].SQL
+	})
+	if n > 0 && len(list) > n {
+		list = list[:n]
+	}
+	return list
+}
+
+// ResetSQLStats 清空 SQL 统计
+func (t *CRUDTool) ResetSQLStats() {
+	t.sqlStats.mu.Lock()
+	t.sqlStats.stats = nil
+	t.sqlStats.mu.Unlock()
+}
+
+func (t *CRUDTool) slowQueryThreshold() time.Duration {
+	if t.SlowQueryThreshold <= 0 {
+		return DefaultSlowQueryThreshold
+	}
+	return t.SlowQueryThreshold
+}
+
+// SQLLogger 返回通过 t.Logger 输出的 GORM 日志，level 为记录的最低级别
+//
+//	db.Logger = cruder.SQLLogger(gormlogger.Warn)
+func (t *CRUDTool) SQLLogger(level gormlogger.LogLevel) gormlogger.Interface {
+	return &sqlLogger{tool: t, level: level}
+}
+
+type sqlLogger struct {
+	tool  *CRUDTool
+	level gormlogger.LogLevel
+}
+
+func (l *sqlLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	return &sqlLogger{tool: l.tool, level: level}
+}
+
+func (l *sqlLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Info {
+		l.tool.Logger.Info(ctx, fmt.Sprintf(msg, data...), map[string]interface{}{"caller": sqlCaller()})
+	}
+}
+
+func (l *sqlLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		l.tool.Logger.Warn(ctx, fmt.Sprintf(msg, data...), map[string]interface{}{"caller": sqlCaller()})
+	}
+}
+
+func (l *sqlLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Error {
+		l.tool.Logger.Error(ctx, fmt.Sprintf(msg, data...), map[string]interface{}{"caller": sqlCaller()})
+	}
+}
+
+// Trace 每条语句执行后由 GORM 调用
+func (l *sqlLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	elapsed := time.Since(begin)
+	threshold := l.tool.slowQueryThreshold()
+	failed := err != nil && !errors.Is(err, gorm.ErrRecordNotFound)
+	slow := elapsed >= threshold
+
+	logged := (failed && l.level >= gormlogger.Error) || (slow && l.level >= gormlogger.Warn) || l.level >= gormlogger.Info
+	if l.tool.DisableSQLStats && !logged {
+		return
+	}
+
+	sql, rows := fc()
+	caller := ""
+	callerOnce := func() string {
+		if caller == "" {
+			caller = sqlCaller()
+		}
+		return caller
+	}
+	if !l.tool.DisableSQLStats {
+		// GORM 记录日志时 SQLite、MySQL 的字符串参数用双引号，PostgreSQL 用单引号（双引号为标识符）
+		doubleQuoted := l.tool.DB.Dialector.Name() != "postgres"
+		l.tool.sqlStats.observe(normalizeSQL(sql, doubleQuoted), elapsed, rows, slow, failed, callerOnce)
+	}
+
+	if !logged {
+		return
+	}
+	fields := map[string]interface{}{
+		"sql":      sql,
+		"duration": elapsed.String(),
+		"rows":     rows, // -1 表示未知
+		"caller":   callerOnce(),
+	}
+	switch {
+	case failed && l.level >= gormlogger.Error:
+		fields["error"] = err.Error()
+		l.tool.Logger.Error(ctx, "SQL 执行失败", fields)
+	case slow && l.level >= gormlogger.Warn:
+		fields["slow_threshold"] = threshold.String()
+		if l.tool.ExplainSlowQueries {
+			plan, err := l.tool.explainQuery(ctx, sql)
+			if err != nil {
+				fields["explain_error"] = err.Error()
+			} else if plan != "" {
+				fields["query_plan"] = plan
+			}
+		}
+		l.tool.Logger.Warn(ctx, "慢查询", fields)
+	case l.level >= gormlogger.Info:
+		l.tool.Logger.Debug(ctx, "SQL", fields)
+	}
+}
+
+// explainQuery 对 SQLite 的 SELECT 语句执行 EXPLAIN QUERY PLAN，其他数据库和语句返回空字符串
+// 直接使用 database/sql 连接执行，不经过 GORM 回调，不会再次触发 SQL 日志和追踪
+func (t *CRUDTool) explainQuery(ctx context.Context, sql string) (string, error) {
+	if t.DB.Dialector.Name() != "sqlite" {
+		return "", nil
+	}
+	trimmed := strings.TrimSpace(sql)
+	if len(trimmed) < 6 || !strings.EqualFold(trimmed[:6], "SELECT") {
+		return "", nil
+	}
+	sqlDB, err := t.DB.DB()
+	if err != nil {
+		return "", err
+	}
+	// 事务中的慢查询占用着连接，连接池已满时等待不超过 explainTimeout
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), explainTimeout)
+	defer cancel()
+
+	rows, err := sqlDB.QueryContext(ctx, "EXPLAIN QUERY PLAN "+trimmed)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var steps []string
+	for rows.Next() {
+		var id, parent, notUsed int
+		var detail string
+		if err := rows.Scan(&id, &parent, &notUsed, &detail); err != nil {
+			return "", err
+		}
+		steps = append(steps, detail)
+	}
+	return strings.Join(steps, "; "), rows.Err()
+}
+
+var (
+	sqlStringLiteral = regexp.MustCompile(`'(?:[^']|'')*'`)
+	sqlDoubleQuoted  = regexp.MustCompile(`"(?:[^"\\]|\\.|"")*"`)
+	sqlNumber        = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	sqlPlaceholders  = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+	sqlValueGroups   = regexp.MustCompile(`\(\?\)(?:\s*,\s*\(\?\))+`)
+	sqlSpaces        = regexp.MustCompile(`\s+`)
+)
+
+// normalizeSQL 把字符串和数字参数替换为 ?，IN 列表和多行 VALUES 合并为一个 (?)，用于按语句统计
+// doubleQuoted 为 true 时双引号内的内容也作为字符串参数
+func normalizeSQL(sql string, doubleQuoted bool) string {
+	sql = sqlStringLiteral.ReplaceAllString(sql, "?")
+	if doubleQuoted {
+		sql = sqlDoubleQuoted.ReplaceAllString(sql, "?")
+	}
+	sql = sqlNumber.ReplaceAllString(sql, "?")
+	sql = sqlPlaceholders.ReplaceAllString(sql, "(?)")
+	sql = sqlValueGroups.ReplaceAllString(sql, "(?)")
+	return strings.TrimSpace(sqlSpaces.ReplaceAllString(sql, " "))
+}
+
+// sqlSourceDirs gormtool 和 GORM 的源码目录，查找调用位置时跳过
+var sqlSourceDirs = func() [2]string {
+	_, file, _, _ := runtime.Caller(0)
+	open := runtime.FuncForPC(reflect.ValueOf(gorm.Open).Pointer())
+	gormFile, _ := open.FileLine(open.Entry())
+	return [2]string{filepath.Dir(file) + "/", filepath.Dir(gormFile) + "/"}
+}()
+
+// sqlCaller 返回第一个不在 GORM 和 gormtool 中的调用位置，全部在其中时返回 gormtool 中最外层的位置
+func sqlCaller() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	fallback := ""
+	for {
+		frame, more := frames.Next()
+		inGorm := strings.HasPrefix(frame.File, sqlSourceDirs[1])
+		inTool := strings.HasPrefix(frame.File, sqlSourceDirs[0])
+		if !inGorm && !inTool && frame.File != "" {
+			if strings.Contains(frame.File, "/runtime/") || strings.Contains(frame.File, "/gin-gonic/") {
+				break
+			}
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if inTool {
+			fallback = fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			break
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,58 @@
+// gormtool\gorm_logger_test.go
+package gormtool
+
+import (
+	"strings"
+	"testing"
+
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func TestNormalizeSQL(t *testing.T) {
+	tests := []struct {
+		sql          string
+		doubleQuoted bool
+		want         string
+	}{
+		{"SELECT * FROM `users` WHERE name = \"li\" AND id IN (1,2, 3)", true, "SELECT * FROM `users` WHERE name = ? AND id IN (?)"},
+		{"SELECT * FROM \"users\" WHERE name = 'it''s' LIMIT 10", false, "SELECT * FROM \"users\" WHERE name = ? LIMIT ?"},
+		{"INSERT INTO t (a,b) VALUES (1,'x'),(2,'y')", true, "INSERT INTO t (a,b) VALUES (?)"},
+	}
+	for _, tt := range tests {
+		if got := normalizeSQL(tt.sql, tt.doubleQuoted); got != tt.want {
+			t.Errorf("normalizeSQL(%q) = %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+}
+
+// 统计按归一化 SQL 合并；DisableSQLStats 时不统计
+func TestSQLStats(t *testing.T) {
+	env := newTestEnv(t)
+	env.tool.DB.Logger = env.tool.SQLLogger(gormlogger.Silent)
+	env.seed(t, "a", "b")
+
+	env.tool.DB.First(&testItem{}, 1)
+	env.tool.DB.First(&testItem{}, 2)
+
+	var found *SQLStats
+	for _, s := range env.tool.SlowestQueries(0) {
+		if strings.HasPrefix(s.SQL, "SELECT * FROM `test_items` WHERE `test_items`.`id` = ?") {
+			s := s
+			found = &s
+		}
+	}
+	if found == nil || found.Count != 2 {
+		t.Fatalf("SlowestQueries = %+v", env.tool.SlowestQueries(0))
+	}
+	// 测试文件与 gormtool 在同一目录，调用位置是 gormtool 之外的第一层
+	if found.Caller == "" || strings.HasPrefix(found.Caller, sqlSourceDirs[1]) {
+		t.Fatalf("Caller = %q", found.Caller)
+	}
+
+	env.tool.ResetSQLStats()
+	env.tool.DisableSQLStats = true
+	env.tool.DB.First(&testItem{}, 1)
+	if got := env.tool.SlowestQueries(0); len(got) != 0 {
+		t.Fatalf("DisableSQLStats 时 SlowestQueries = %+v", got)
+	}
+}
@@ -0,0 +1,420 @@
+// gormtool\handler.go
+package gormtool
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// gin 适配层：解析请求参数，调用 service.go 中的服务方法，并把结果和错误映射为 Response
+
+// ParseID 解析路由参数中的 id
+func ParseID(c *gin.Context) (uint, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, NewError(ErrKindInvalidID, "parse_id", "无效的ID", err)
+	}
+	return uint(id), nil
+}
+
+// BindJSON 绑定请求体，失败时返回 ErrKindBind
+func BindJSON(c *gin.Context, obj interface{}) error {
+	if err := c.ShouldBindJSON(obj); err != nil {
+		return NewError(ErrKindBind, "bind", "参数错误", err)
+	}
+	return nil
+}
+
+// RespondError 把服务层错误映射为 Response，err 为 nil 时按内部错误返回
+func RespondError(c *gin.Context, err error) {
+	var e *Error
+	if !errors.As(err, &e) {
+		var ok bool
+		if e, ok = wrapDBError("", "操作失败", err).(*Error); !ok {
+			e = NewError(ErrKindInternal, "", "", nil)
+		}
+	}
+
+	message := e.Message
+	if message == "" {
+		message = http.StatusText(e.Status())
+	}
+	c.JSON(e.Status(), Response{
+		Code:      e.Status(),
+		Message:   message,
+		Data:      e.Details,
+		ErrorCode: e.Kind,
+		RequestID: requestID(c),
+	})
+}
+
+// Respond 返回成功响应
+func Respond(c *gin.Context, code int, message string, data interface{}) {
+	c.JSON(code, Response{
+		Code:      code,
+		Message:   message,
+		Data:      data,
+		RequestID: requestID(c),
+	})
+}
+
+// RespondPage 返回带分页信息的成功响应
+func RespondPage(c *gin.Context, message string, data interface{}, page *Pagination) {
+	c.JSON(http.StatusOK, Response{
+		Code:      http.StatusOK,
+		Message:   message,
+		Data:      data,
+		Page:      page,
+		RequestID: requestID(c),
+	})
+}
+
+// parseID 解析 id，失败时记录日志并返回错误响应
+func (t *CRUDTool) parseID(c *gin.Context, operation string, model interface{}, start time.Time) (uint, error) {
+	id, err := ParseID(c)
+	if err != nil {
+		t.logService(c.Request.Context(), operation, model, start, err, map[string]interface{}{
+			"id": c.Param("id"),
+		})
+		RespondError(c, err)
+		return 0, err
+	}
+	return id, nil
+}
+
+// bindJSON 绑定请求体，失败时记录日志并返回错误响应
+func (t *CRUDTool) bindJSON(c *gin.Context, operation string, obj interface{}, start time.Time) error {
+	if err := BindJSON(c, obj); err != nil {
+		t.logService(c.Request.Context(), operation, obj, start, err, nil)
+		RespondError(c, err)
+		return err
+	}
+	return nil
+}
+
+// Transaction 事务包装器
+func (t *CRUDTool) Transaction(c *gin.Context, fn TxFunc) {
+	start := time.Now()
+	ctx := t.startOperation(c.Request.Context(), "transaction", nil)
+	var err error
+
+	defer func() {
+		t.LogOperation(ctx, "transaction", nil, time.Since(start), err, nil)
+	}()
+
+	err = t.WithTransaction(ctx, fn)
+	if err != nil {
+		RespondError(c, wrapDBError("transaction", "事务执行失败", err))
+		return
+	}
+
+	Respond(c, http.StatusOK, "操作成功", nil)
+}
+
+// GetByIDWithRelations 根据ID查询单条记录（支持预加载关系）
+func (t *CRUDTool) GetByIDWithRelations(c *gin.Context, model interface{}, relations []string) error {
+	id, err := t.parseID(c, "get_by_id", model, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if _, err := t.FindByID(c.Request.Context(), model, id, FindOptions{Preloads: relations, NoCache: true}); err != nil {
+		RespondError(c, err)
+		return err
+	}
+
+	Respond(c, http.StatusOK, "查询成功", model)
+	return nil
+}
+
+// CreateWithRelations 创建记录（支持关联创建）
+func (t *CRUDTool) CreateWithRelations(c *gin.Context, model interface{}, relations []string) error {
+	if err := t.bindJSON(c, "create", model, time.Now()); err != nil {
+		return err
+	}
+
+	if err := t.CreateRecord(c.Request.Context(), model, relations...); err != nil {
+		RespondError(c, err)
+		return err
+	}
+
+	Respond(c, http.StatusCreated, "创建成功", model)
+	return nil
+}
+
+// UpdateWithRelations 更新记录（支持关联更新）
+func (t *CRUDTool) UpdateWithRelations(c *gin.Context, model interface{}, relations []string) error {
+	return t.update(c, model, relations)
+}
+
+// GetRelated 获取关联记录
+func (t *CRUDTool) GetRelated(c *gin.Context, model interface{}, associationName string, result interface{}) error {
+	id, err := t.parseID(c, "get_related", model, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if err := t.FindRelated(c.Request.Context(), model, id, associationName, result); err != nil {
+		RespondError(c, err)
+		return err
+	}
+
+	Respond(c, http.StatusOK, "获取关联记录成功", result)
+	return nil
+}
+
+// AddRelation 添加关联关系
+func (t *CRUDTool) AddRelation(c *gin.Context, model interface{}, associationName string, relatedModel interface{}) error {
+	start := time.Now()
+	id, err := t.parseID(c, "add_relation", model, start)
+	if err != nil {
+		return err
+	}
+
+	if err := t.bindJSON(c, "add_relation", relatedModel, start); err != nil {
+		return err
+	}
+
+	if err := t.AppendRelation(c.Request.Context(), model, id, associationName, relatedModel); err != nil {
+		RespondError(c, err)
+		return err
+	}
+
+	Respond(c, http.StatusOK, "添加关联成功", nil)
+	return nil
+}
+
+// GetByID 根据ID查询（带缓存），支持 ?fields= 稀疏字段，此时直接查询数据库
+func (t *CRUDTool) GetByID(c *gin.Context, model interface{}, preloads ...string) error {
+	id, err := t.parseID(c, "get_by_id", model, time.Now())
+	if err != nil {
+		return err
+	}
+
+	sparse := &QueryBuilder{Preloads: preloads}
+	sparse.Select, sparse.SelectRelations = parseFieldsParams(c.Request.URL.Query())
+
+	cached, err := t.FindByID(c.Request.Context(), model, id, FindOptions{
+		Preloads:        preloads,
+		Select:          sparse.Select,
+		SelectRelations: sparse.SelectRelations,
+	})
+	if err != nil {
+		RespondError(c, err)
+		return err
+	}
+
+	data, err := t.ProjectFields(model, sparse)
+	if err != nil {
+		RespondError(c, wrapDBError("get_by_id", "查询失败", err))
+		return err
+	}
+
+	message := "查询成功"
+	if cached {
+		message = "查询成功（缓存）"
+	}
+	Respond(c, http.StatusOK, message, data)
+	return nil
+}
+
+// GetByIDWithSoftDelete 支持软删除的查询
+func (t *CRUDTool) GetByIDWithSoftDelete(c *gin.Context, model interface{}, preloads ...string) error {
+	id, err := t.parseID(c, "get_by_id_soft_delete", model, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if _, err := t.FindByID(c.Request.Context(), model, id, FindOptions{Preloads: preloads, Unscoped: true}); err != nil {
+		RespondError(c, err)
+		return err
+	}
+
+	Respond(c, http.StatusOK, "查询成功", model)
+	return nil
+}
+
+// GetByQueryBuilder 使用查询构建器（支持分页）
+// URL 中的 filter/sort/include/fields/search 参数会追加到 qb 上，见 ParseQueryParams；
+// 带有 cursor 参数（第一页传空值 ?cursor=）时使用游标分页，with_total=true 时统计总数
+// 全文搜索时每条结果附带 _highlight 高亮片段
+func (t *CRUDTool) GetByQueryBuilder(c *gin.Context, models interface{}, qb *QueryBuilder) error {
+	parsed, err := ParseQueryParams(c.Request.URL.Query())
+	if err != nil {
+		t.logService(c.Request.Context(), "get_by_query_builder", models, time.Now(), err, nil)
+		RespondError(c, err)
+		return err
+	}
+	qb = qb.Merge(parsed)
+
+	// 分页参数处理
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pagesize", "10"))
+
+	var pagination *Pagination
+	if cursor, ok := c.GetQuery("cursor"); ok {
+		withTotal, _ := strconv.ParseBool(c.Query("with_total"))
+		pagination, err = t.FindCursorPage(c.Request.Context(), models, qb, CursorRequest{
+			Cursor:    cursor,
+			PageSize:  pageSize,
+			WithTotal: withTotal,
+		})
+	} else {
+		pagination, err = t.FindPage(c.Request.Context(), models, qb, page, pageSize)
+	}
+	if err != nil {
+		RespondError(c, err)
+		return err
+	}
+
+	data, err := t.ProjectFields(models, qb)
+	if err == nil && qb.Search != "" {
+		data, err = t.attachHighlights(c.Request.Context(), models, data, qb.Search)
+	}
+	if err != nil {
+		RespondError(c, wrapDBError("get_by_query_builder", "查询失败", err))
+		return err
+	}
+
+	RespondPage(c, "查询成功", data, pagination)
+	return nil
+}
+
+// Create 创建记录
+func (t *CRUDTool) Create(c *gin.Context, model interface{}) error {
+	if err := t.bindJSON(c, "create", model, time.Now()); err != nil {
+		return err
+	}
+
+	if err := t.CreateRecord(c.Request.Context(), model); err != nil {
+		RespondError(c, err)
+		return err
+	}
+
+	Respond(c, http.StatusCreated, "创建成功", model)
+	return nil
+}
+
+// UpdateByID 更新记录（带缓存失效）
+func (t *CRUDTool) UpdateByID(c *gin.Context, model interface{}) error {
+	return t.update(c, model, nil)
+}
+
+func (t *CRUDTool) update(c *gin.Context, model interface{}, relations []string) error {
+	id, err := t.parseID(c, "update_by_id", model, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// 记录存在后再绑定请求体，覆盖到已加载的模型上
+	err = t.UpdateRecord(c.Request.Context(), model, id, func(m interface{}) error {
+		return BindJSON(c, m)
+	}, relations...)
+	if err != nil {
+		RespondError(c, err)
+		return err
+	}
+
+	Respond(c, http.StatusOK, "更新成功", model)
+	return nil
+}
+
+// SoftDeleteByID 软删除
+func (t *CRUDTool) SoftDeleteByID(c *gin.Context, model interface{}) error {
+	id, err := t.parseID(c, "soft_delete", model, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if err := t.SoftDelete(c.Request.Context(), model, id); err != nil {
+		RespondError(c, err)
+		return err
+	}
+
+	Respond(c, http.StatusOK, "删除成功", nil)
+	return nil
+}
+
+// HardDeleteByID 硬删除
+func (t *CRUDTool) HardDeleteByID(c *gin.Context, model interface{}) error {
+	id, err := t.parseID(c, "hard_delete", model, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if err := t.HardDelete(c.Request.Context(), model, id); err != nil {
+		RespondError(c, err)
+		return err
+	}
+
+	Respond(c, http.StatusOK, "永久删除成功", nil)
+	return nil
+}
+
+// RestoreSoftDelete 恢复软删除的记录
+func (t *CRUDTool) RestoreSoftDelete(c *gin.Context, model interface{}) error {
+	id, err := t.parseID(c, "restore", model, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if err := t.Restore(c.Request.Context(), model, id); err != nil {
+		RespondError(c, err)
+		return err
+	}
+
+	Respond(c, http.StatusOK, "恢复成功", nil)
+	return nil
+}
+
+// BatchOperation 批量操作（区分软删除和硬删除）
+func (t *CRUDTool) BatchOperation(c *gin.Context, models interface{}, operation string) error {
+	start := time.Now()
+	name := batchOperationName(operation)
+	if name == "batch_invalid" {
+		err := NewError(ErrKindInvalidArgument, name, "不支持的批量操作", nil)
+		t.logService(c.Request.Context(), name, models, start, err, map[string]interface{}{
+			"batch_op": operation,
+		})
+		RespondError(c, err)
+		return err
+	}
+	if err := t.bindJSON(c, name, models, start); err != nil {
+		return err
+	}
+
+	affected, err := t.Batch(c.Request.Context(), models, operation)
+	if err != nil {
+		RespondError(c, err)
+		return err
+	}
+
+	Respond(c, http.StatusOK, "批量操作成功", affected)
+	return nil
+}
+
+// PatchByID 部分更新记录，请求体为 {"字段": 值}
+func (t *CRUDTool) PatchByID(c *gin.Context, model interface{}) error {
+	start := time.Now()
+	id, err := t.parseID(c, "patch_by_id", model, start)
+	if err != nil {
+		return err
+	}
+
+	var fields map[string]interface{}
+	if err := t.bindJSON(c, "patch_by_id", &fields, start); err != nil {
+		return err
+	}
+
+	if err := t.PatchRecord(c.Request.Context(), model, id, fields); err != nil {
+		RespondError(c, err)
+		return err
+	}
+
+	Respond(c, http.StatusOK, "更新成功", model)
+	return nil
+}
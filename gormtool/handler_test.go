@@ -0,0 +1,42 @@
+// gormtool\handler_test.go
+package gormtool
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func TestRespondError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+		wantKind ErrorKind
+	}{
+		{"服务层错误", NewError(ErrKindInvalidArgument, "list", "参数错误", nil), http.StatusBadRequest, ErrKindInvalidArgument},
+		{"记录不存在", gorm.ErrRecordNotFound, http.StatusNotFound, ErrKindNotFound},
+		{"其他错误", errors.New("disk full"), http.StatusInternalServerError, ErrKindDB},
+		{"nil", nil, http.StatusInternalServerError, ErrKindInternal},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		RespondError(c, tt.err)
+
+		var resp Response
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if w.Code != tt.wantCode || resp.Code != tt.wantCode || resp.ErrorCode != tt.wantKind {
+			t.Errorf("%s: status = %d, body = %+v; want %d %s", tt.name, w.Code, resp, tt.wantCode, tt.wantKind)
+		}
+	}
+}
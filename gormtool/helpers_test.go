@@ -0,0 +1,116 @@
+// gormtool\helpers_test.go
+package gormtool
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// testItem 测试用模型
+type testItem struct {
+	ID        uint
+	Name      string
+	Score     int
+	OwnerID   *uint
+	CreatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+// testOwner 测试用模型，一对多关联 testItem
+type testOwner struct {
+	ID    uint
+	Name  string
+	Items []testItem `gorm:"foreignKey:OwnerID"`
+}
+
+// testEnv 内存 SQLite + NewMemoryCache 的 CRUDTool，queries 统计 test_items 表的查询次数
+type testEnv struct {
+	tool    *CRUDTool
+	queries atomic.Int64
+	// queryDelay 每次查询前等待的时间，用于制造并发重叠
+	queryDelay time.Duration
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 内存数据库每个连接独立，只使用一个连接
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&testItem{}, &testOwner{}); err != nil {
+		t.Fatal(err)
+	}
+
+	env := &testEnv{}
+	err = db.Callback().Query().Before("gorm:query").Register("test:count", func(db *gorm.DB) {
+		if db.Statement.Table == "test_items" {
+			env.queries.Add(1)
+			time.Sleep(env.queryDelay)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env.tool = NewCRUDTool(db, NewMemoryCache(1000), nil)
+	env.tool.EnableLog = false
+	return env
+}
+
+// seed 插入名称为 names 的记录，Score 依次为 1、2、3 ...
+func (e *testEnv) seed(t *testing.T, names ...string) []testItem {
+	t.Helper()
+	items := make([]testItem, len(names))
+	for i, name := range names {
+		items[i] = testItem{Name: name, Score: i + 1}
+	}
+	if err := e.tool.DB.Create(&items).Error; err != nil {
+		t.Fatal(err)
+	}
+	return items
+}
+
+// newTestRouter 返回测试模式的 gin 引擎
+func newTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	return gin.New()
+}
+
+// doRequest 发送请求并解析 Response，body 为空时不带请求体
+func doRequest(t *testing.T, h http.Handler, method, target, body string) (*httptest.ResponseRecorder, Response) {
+	t.Helper()
+	var req *http.Request
+	if body == "" {
+		req = httptest.NewRequest(method, target, nil)
+	} else {
+		req = httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	var resp Response
+	if strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s %s: %v: %s", method, target, err, w.Body.String())
+		}
+	}
+	return w, resp
+}
@@ -0,0 +1,188 @@
+// gormtool\logger_rotate.go
+package gormtool
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotatingFile 按大小和时间切换的日志文件
+// 切换时当前文件重命名为 {文件名}.{时间}{扩展名}（如 app.20260102-150405.000.log），再创建新文件
+type RotatingFile struct {
+	mu          sync.Mutex
+	path        string
+	maxSize     int64
+	rotateEvery time.Duration
+	maxBackups  int
+
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+// NewRotatingFile 打开（或追加）日志文件，maxSize 为字节数，0 表示不按大小切换；
+// rotateEvery 为 0 表示不按时间切换；maxBackups 为 0 表示保留所有旧文件
+func NewRotatingFile(path string, maxSize int64, rotateEvery time.Duration, maxBackups int) (*RotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f := &RotatingFile{path: path, maxSize: maxSize, rotateEvery: rotateEvery, maxBackups: maxBackups}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size, f.openedAt = file, info.Size(), time.Now()
+	// 追加已有文件时按修改时间计算，重启不会推迟按时间切换
+	if info.Size() > 0 {
+		f.openedAt = info.ModTime()
+	}
+	return nil
+}
+
+// Write 写入一条日志，写入前检查是否需要切换；单条日志不会被拆到两个文件
+// 切换失败时继续写入原文件（下次写入时重试切换），并返回切换的错误
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	var rotateErr error
+	if f.shouldRotate(int64(len(p))) {
+		rotateErr = f.rotate()
+		if f.file == nil {
+			return 0, rotateErr
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	if err != nil {
+		return n, err
+	}
+	return n, rotateErr
+}
+
+func (f *RotatingFile) shouldRotate(next int64) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.maxSize > 0 && f.size+next > f.maxSize {
+		return true
+	}
+	return f.rotateEvery > 0 && time.Since(f.openedAt) >= f.rotateEvery
+}
+
+// renameFile 重命名文件，测试中替换以模拟失败
+var renameFile = os.Rename
+
+// backupStamp 旧文件名中的时间格式
+const backupStamp = "20060102-150405.000"
+
+// rotate 重命名当前文件并打开新文件，调用方需持有锁
+// 任何一步失败时重新以追加方式打开原文件，之后的写入不受影响
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		f.file = nil
+		return f.reopen(err)
+	}
+	f.file = nil
+
+	ext := filepath.Ext(f.path)
+	base := strings.TrimSuffix(f.path, ext)
+	stamp := time.Now().Format(backupStamp)
+	backup := fmt.Sprintf("%s.%s%s", base, stamp, ext)
+	// 同一毫秒内多次切换时加序号
+	for i := 1; fileExists(backup); i++ {
+		backup = fmt.Sprintf("%s.%s_%03d%s", base, stamp, i, ext)
+	}
+	if err := renameFile(f.path, backup); err != nil {
+		return f.reopen(err)
+	}
+	if err := f.open(); err != nil {
+		return f.reopen(err)
+	}
+	f.openedAt = time.Now()
+	f.removeOldBackups(base, ext)
+	return nil
+}
+
+// reopen 切换失败后重新打开原文件，返回切换的错误（以及重新打开的错误）
+func (f *RotatingFile) reopen(err error) error {
+	if openErr := f.open(); openErr != nil {
+		return fmt.Errorf("gormtool: 日志文件切换失败: %w（重新打开失败: %v）", err, openErr)
+	}
+	return fmt.Errorf("gormtool: 日志文件切换失败: %w", err)
+}
+
+// removeOldBackups 删除超过 maxBackups 的旧文件（按文件名中的时间排序）
+// 只处理 rotate 生成的文件名（{文件名}.{时间}[_序号]{扩展名}），同目录下的其他文件不受影响
+func (f *RotatingFile) removeOldBackups(base, ext string) {
+	if f.maxBackups <= 0 {
+		return
+	}
+	matches, err := filepath.Glob(base + ".*" + ext)
+	if err != nil {
+		return
+	}
+	prefix := filepath.Base(base) + "."
+	var backups []string
+	for _, m := range matches {
+		name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(m), prefix), ext)
+		if isBackupStamp(name) {
+			backups = append(backups, m)
+		}
+	}
+	if len(backups) <= f.maxBackups {
+		return
+	}
+	sort.Strings(backups)
+	for _, old := range backups[:len(backups)-f.maxBackups] {
+		os.Remove(old)
+	}
+}
+
+// Close 关闭当前文件
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// isBackupStamp 判断是否为 backupStamp 格式的时间，可带 _序号
+func isBackupStamp(s string) bool {
+	stamp, seq, hasSeq := strings.Cut(s, "_")
+	if hasSeq && (len(seq) != 3 || strings.Trim(seq, "0123456789") != "") {
+		return false
+	}
+	_, err := time.Parse(backupStamp, stamp)
+	return err == nil && len(stamp) == len(backupStamp)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,139 @@
+// gormtool\logger_rotate_test.go
+package gormtool
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// backupFiles 返回目录中 rotate 生成的旧文件名（已排序）
+func backupFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		name := strings.TrimSuffix(strings.TrimPrefix(e.Name(), "app."), ".log")
+		if isBackupStamp(name) {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRotatingFileRotatesBySize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	// 同目录下名称相近的其他文件不能被清理
+	unrelated := filepath.Join(dir, "app.old.log")
+	if err := os.WriteFile(unrelated, []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := NewRotatingFile(path, 10, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	for i := 0; i < 5; i++ {
+		if _, err := f.Write([]byte("0123456789")); err != nil {
+			t.Fatalf("第 %d 次写入: %v", i+1, err)
+		}
+	}
+
+	if got := backupFiles(t, dir); len(got) != 2 {
+		t.Fatalf("backups = %v, want 2", got)
+	}
+	if _, err := os.Stat(unrelated); err != nil {
+		t.Fatalf("无关文件被删除: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "0123456789" {
+		t.Fatalf("当前文件内容 = %q", data)
+	}
+}
+
+func TestRotatingFileRotatesByTime(t *testing.T) {
+	dir := t.TempDir()
+	f, err := NewRotatingFile(filepath.Join(dir, "app.log"), 0, 20*time.Millisecond, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	f.Write([]byte("a\n"))
+	f.Write([]byte("b\n"))
+	if got := backupFiles(t, dir); len(got) != 0 {
+		t.Fatalf("未到切换时间: backups = %v", got)
+	}
+	time.Sleep(30 * time.Millisecond)
+	f.Write([]byte("c\n"))
+	if got := backupFiles(t, dir); len(got) != 1 {
+		t.Fatalf("backups = %v, want 1", got)
+	}
+}
+
+// 重命名失败时继续写入原文件，恢复后正常切换
+func TestRotatingFileRenameFailure(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	f, err := NewRotatingFile(path, 4, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	errRename := errors.New("rename failed")
+	renameFile = func(string, string) error { return errRename }
+	defer func() { renameFile = os.Rename }()
+
+	f.Write([]byte("aaaa"))
+	n, err := f.Write([]byte("bbbb"))
+	if !errors.Is(err, errRename) || n != 4 {
+		t.Fatalf("Write = %d, %v; want 4 and the rename error", n, err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "aaaabbbb" {
+		t.Fatalf("原文件内容 = %q", data)
+	}
+
+	renameFile = os.Rename
+	if _, err := f.Write([]byte("cccc")); err != nil {
+		t.Fatalf("恢复后写入: %v", err)
+	}
+	if got := backupFiles(t, dir); len(got) != 1 {
+		t.Fatalf("backups = %v, want 1", got)
+	}
+	data, _ = os.ReadFile(path)
+	if string(data) != "cccc" {
+		t.Fatalf("当前文件内容 = %q", data)
+	}
+}
+
+func TestIsBackupStamp(t *testing.T) {
+	tests := map[string]bool{
+		"20260102-150405.000":     true,
+		"20260102-150405.123_001": true,
+		"old":                     false,
+		"20260102-150405":         false,
+		"20260102-150405.000_1":   false,
+		"20260102-150405.000.bak": false,
+	}
+	for in, want := range tests {
+		if got := isBackupStamp(in); got != want {
+			t.Errorf("isBackupStamp(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+// gormtool\logger_slog.go
+package gormtool
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SlogLogger 基于 log/slog 的 Logger 实现
+type SlogLogger struct {
+	logger *slog.Logger
+	closer io.Closer // 日志文件，没有时为 nil
+}
+
+// NewSlogLogger 把已有的 *slog.Logger 适配为 Logger，输出时带上 ctx 中的请求字段
+func NewSlogLogger(logger *slog.Logger) *SlogLogger {
+	return &SlogLogger{logger: slog.New(contextHandler{logger.Handler()})}
+}
+
+// LogConfig 日志配置
+type LogConfig struct {
+	// 最低级别：debug、info、warn、error，为空时为 info
+	Level string
+	// 输出格式：text 或 json，为空时为 text
+	Format string
+	// 日志文件路径，为空时输出到标准输出
+	File string
+	// 单个文件最大大小（MB），超过后切换新文件，0 表示不按大小切换
+	MaxSizeMB int
+	// 按时间切换的间隔（如 24h），0 表示不按时间切换
+	RotateEvery time.Duration
+	// 保留的旧文件个数，0 表示全部保留
+	MaxBackups int
+}
+
+// NewLogger 按配置创建 SlogLogger，写文件时不再使用后调用 Close
+func NewLogger(cfg LogConfig) (*SlogLogger, error) {
+	level, err := ParseLogLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	var out io.Writer = os.Stdout
+	var closer io.Closer
+	if cfg.File != "" {
+		file, err := NewRotatingFile(cfg.File, int64(cfg.MaxSizeMB)<<20, cfg.RotateEvery, cfg.MaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		out, closer = file, file
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "", "text":
+		handler = slog.NewTextHandler(out, opts)
+	case "json":
+		handler = slog.NewJSONHandler(out, opts)
+	default:
+		if closer != nil {
+			closer.Close()
+		}
+		return nil, fmt.Errorf("gormtool: 未知的日志格式 %q（可选 text、json）", cfg.Format)
+	}
+	return &SlogLogger{logger: slog.New(contextHandler{handler}), closer: closer}, nil
+}
+
+// ParseLogLevel 解析日志级别，为空时为 info
+func ParseLogLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if s == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("gormtool: 未知的日志级别 %q（可选 debug、info、warn、error）", s)
+	}
+	return level, nil
+}
+
+// Slog 返回底层的 *slog.Logger，可用于记录业务日志（使用 InfoContext 等方法时同样带上请求字段）
+func (l *SlogLogger) Slog() *slog.Logger {
+	return l.logger
+}
+
+// Close 关闭日志文件
+func (l *SlogLogger) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
+
+func (l *SlogLogger) Debug(ctx context.Context, msg string, fields map[string]interface{}) {
+	l.log(ctx, slog.LevelDebug, msg, fields)
+}
+
+func (l *SlogLogger) Info(ctx context.Context, msg string, fields map[string]interface{}) {
+	l.log(ctx, slog.LevelInfo, msg, fields)
+}
+
+func (l *SlogLogger) Warn(ctx context.Context, msg string, fields map[string]interface{}) {
+	l.log(ctx, slog.LevelWarn, msg, fields)
+}
+
+func (l *SlogLogger) Error(ctx context.Context, msg string, fields map[string]interface{}) {
+	l.log(ctx, slog.LevelError, msg, fields)
+}
+
+// log 字段按名称排序，输出顺序稳定
+func (l *SlogLogger) log(ctx context.Context, level slog.Level, msg string, fields map[string]interface{}) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	attrs := make([]slog.Attr, 0, len(keys))
+	for _, k := range keys {
+		attrs = append(attrs, slog.Any(k, fields[k]))
+	}
+	l.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// contextHandler 在每条记录中加入 ContextLogFields(ctx)，记录中已有的同名字段优先
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	fields := ContextLogFields(ctx)
+	if len(fields) > 0 {
+		r.Attrs(func(a slog.Attr) bool {
+			delete(fields, a.Key)
+			return true
+		})
+		keys := make([]string, 0, len(fields))
+		for k := range fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			r.AddAttrs(slog.Any(k, fields[k]))
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// sampleSuccess 按 LogSampling 决定是否记录成功日志，返回使用的采样比例
+func (t *CRUDTool) sampleSuccess(operation string) (bool, float64) {
+	rate, ok := t.LogSampling[operation]
+	if !ok {
+		if rate, ok = t.LogSampling["*"]; !ok {
+			return true, 1
+		}
+	}
+	if rate >= 1 {
+		return true, 1
+	}
+	return rate > 0 && rand.Float64() < rate, rate
+}
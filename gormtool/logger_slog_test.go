@@ -0,0 +1,60 @@
+// gormtool\logger_slog_test.go
+package gormtool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestSampleSuccess(t *testing.T) {
+	tool := &CRUDTool{LogSampling: map[string]float64{"get_by_id": 0, "list": 1, "*": 0.5}}
+
+	if keep, rate := tool.sampleSuccess("get_by_id"); keep || rate != 0 {
+		t.Fatalf("get_by_id = %v, %v; want false, 0", keep, rate)
+	}
+	if keep, rate := tool.sampleSuccess("list"); !keep || rate != 1 {
+		t.Fatalf("list = %v, %v; want true, 1", keep, rate)
+	}
+	if _, rate := tool.sampleSuccess("create"); rate != 0.5 {
+		t.Fatalf("create rate = %v, want 0.5 (\"*\")", rate)
+	}
+	if keep, rate := (&CRUDTool{}).sampleSuccess("create"); !keep || rate != 1 {
+		t.Fatalf("未配置采样 = %v, %v; want true, 1", keep, rate)
+	}
+}
+
+// 失败日志不采样，成功日志带 sample_rate
+func TestLogOperationSampling(t *testing.T) {
+	var buf bytes.Buffer
+	tool := NewCRUDTool(nil, nil, NewSlogLogger(slog.New(slog.NewJSONHandler(&buf, nil))))
+	tool.LogSampling = map[string]float64{"get_by_id": 0}
+
+	tool.LogOperation(context.Background(), "get_by_id", nil, 0, nil, nil)
+	if buf.Len() != 0 {
+		t.Fatalf("采样比例为 0 的成功日志被记录: %s", buf.String())
+	}
+
+	tool.LogOperation(context.Background(), "get_by_id", nil, 0, errBackend, nil)
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("失败日志未记录: %v", err)
+	}
+	if entry["level"] != "ERROR" || entry["operation"] != "get_by_id" {
+		t.Fatalf("entry = %v", entry)
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	for in, want := range map[string]slog.Level{"": slog.LevelInfo, "debug": slog.LevelDebug, "WARN": slog.LevelWarn} {
+		got, err := ParseLogLevel(in)
+		if err != nil || got != want {
+			t.Errorf("ParseLogLevel(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseLogLevel("verbose"); err == nil {
+		t.Error("ParseLogLevel(\"verbose\") 应返回错误")
+	}
+}
@@ -0,0 +1,368 @@
+// gormtool\metrics_prometheus.go
+package gormtool
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Prometheus 文本格式指标
+// 不依赖 client_golang，计数器和直方图在这里实现，格式见
+// https://prometheus.io/docs/instrumenting/exposition_formats/
+//
+//	gormtool_operations_total{operation,model,status}         LogOperation 记录的操作次数
+//	gormtool_operation_duration_seconds{operation,model}      操作耗时直方图
+//	gormtool_errors_total{type,operation}                     按错误类型（invalid_id、bind_error、not_found、db_error 等）统计
+//	gormtool_http_requests_total{method,route,status}         HTTP 请求数（需要 MetricsMiddleware）
+//	gormtool_http_request_duration_seconds{method,route}      HTTP 请求耗时直方图
+//	gormtool_db_*                                             sql.DBStats
+//	gormtool_cache_*                                          按表名统计的缓存指标和熔断器状态
+
+// DefaultHistogramBuckets 耗时直方图的桶（秒）
+var DefaultHistogramBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// promCounter 带标签的计数器，零值可用
+type promCounter struct {
+	mu     sync.Mutex
+	series map[string]*counterSeries
+}
+
+type counterSeries struct {
+	labelValues []string
+	value       float64
+}
+
+func (c *promCounter) add(v float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.series == nil {
+		c.series = make(map[string]*counterSeries)
+	}
+	s, ok := c.series[key]
+	if !ok {
+		s = &counterSeries{labelValues: labelValues}
+		c.series[key] = s
+	}
+	s.value += v
+}
+
+// snapshot 按标签排序返回所有序列的副本
+func (c *promCounter) snapshot() []counterSeries {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	out := make([]counterSeries, 0, len(c.series))
+	for _, s := range c.series {
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return strings.Join(out[i].labelValues, "\xff") < strings.Join(out[j].labelValues, "\xff")
+	})
+	return out
+}
+
+// promHistogram 带标签的直方图，零值使用 DefaultHistogramBuckets
+type promHistogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	series  map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	labelValues []string
+	counts      []uint64 // 每个桶的计数（非累计），累计值在输出时计算
+	sum         float64
+	count       uint64
+}
+
+func (h *promHistogram) observe(v float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.series == nil {
+		h.series = make(map[string]*histogramSeries)
+		if h.buckets == nil {
+			h.buckets = DefaultHistogramBuckets
+		}
+	}
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{labelValues: labelValues, counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.sum += v
+	s.count++
+}
+
+func (h *promHistogram) snapshot() ([]float64, []histogramSeries) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	out := make([]histogramSeries, 0, len(h.series))
+	for _, s := range h.series {
+		c := *s
+		c.counts = append([]uint64(nil), s.counts...)
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return strings.Join(out[i].labelValues, "\xff") < strings.Join(out[j].labelValues, "\xff")
+	})
+	return h.buckets, out
+}
+
+// operationMetrics CRUDTool 上的 Prometheus 指标
+type operationMetrics struct {
+	operations    promCounter
+	durations     promHistogram
+	errors        promCounter
+	httpRequests  promCounter
+	httpDurations promHistogram
+	httpInFlight  atomic.Int64
+}
+
+// metricModel 指标中的 model 标签：表名，无法解析时为类型名
+func (t *CRUDTool) metricModel(model interface{}) string {
+	if model == nil {
+		return ""
+	}
+	if sch, err := t.ParseSchema(model); err == nil {
+		return sch.Table
+	}
+	return fmt.Sprintf("%T", model)
+}
+
+// observeOperation 记录一次操作，由 LogOperation 调用，不受 EnableLog 影响
+func (t *CRUDTool) observeOperation(operation string, model interface{}, duration time.Duration, err error) {
+	name := t.metricModel(model)
+	status := "success"
+	if err != nil {
+		status = "error"
+		t.metrics.errors.add(1, string(KindOf(err)), operation)
+	}
+	t.metrics.operations.add(1, operation, name, status)
+	t.metrics.durations.observe(duration.Seconds(), operation, name)
+}
+
+// MetricsMiddleware 记录 HTTP 请求数、耗时和正在处理的请求数
+// route 标签为路由模板（如 /users/:id），未匹配的路由统一记为 "unmatched"，避免标签数量无限增长
+func (t *CRUDTool) MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		t.metrics.httpInFlight.Add(1)
+		defer t.metrics.httpInFlight.Add(-1)
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := c.Request.Method
+		t.metrics.httpRequests.add(1, method, route, strconv.Itoa(c.Writer.Status()))
+		t.metrics.httpDurations.observe(time.Since(start).Seconds(), method, route)
+	}
+}
+
+// PrometheusMetrics 以 Prometheus 文本格式输出指标
+// 先写入缓冲区，生成失败时返回 500，而不是已经写出一半的 200 响应
+//
+//	GET /metrics
+func (t *CRUDTool) PrometheusMetrics(c *gin.Context) {
+	var buf bytes.Buffer
+	if err := t.WritePrometheus(c.Request.Context(), &buf); err != nil {
+		t.Logger.Error(c.Request.Context(), "生成 Prometheus 指标失败", map[string]interface{}{
+			"error": err.Error(),
+		})
+		RespondError(c, NewError(ErrKindInternal, "prometheus_metrics", "生成指标失败", err))
+		return
+	}
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", buf.Bytes())
+}
+
+// WritePrometheus 把所有指标以 Prometheus 文本格式写入 w
+func (t *CRUDTool) WritePrometheus(ctx context.Context, w io.Writer) error {
+	pw := &promWriter{w: bufio.NewWriter(w)}
+
+	pw.counterVec("gormtool_operations_total", "Operations recorded by LogOperation.",
+		[]string{"operation", "model", "status"}, &t.metrics.operations)
+	pw.histogramVec("gormtool_operation_duration_seconds", "Operation latency in seconds.",
+		[]string{"operation", "model"}, &t.metrics.durations)
+	pw.counterVec("gormtool_errors_total", "Operation errors by error type.",
+		[]string{"type", "operation"}, &t.metrics.errors)
+
+	pw.counterVec("gormtool_http_requests_total", "HTTP requests handled.",
+		[]string{"method", "route", "status"}, &t.metrics.httpRequests)
+	pw.histogramVec("gormtool_http_request_duration_seconds", "HTTP request latency in seconds.",
+		[]string{"method", "route"}, &t.metrics.httpDurations)
+	pw.single("gormtool_http_requests_in_flight", "gauge", "HTTP requests currently being handled.",
+		float64(t.metrics.httpInFlight.Load()))
+
+	if sqlDB, err := t.DB.DB(); err == nil {
+		stats := sqlDB.Stats()
+		pw.single("gormtool_db_max_open_connections", "gauge", "Maximum number of open connections to the database.", float64(stats.MaxOpenConnections))
+		pw.single("gormtool_db_open_connections", "gauge", "The number of established connections both in use and idle.", float64(stats.OpenConnections))
+		pw.single("gormtool_db_in_use_connections", "gauge", "The number of connections currently in use.", float64(stats.InUse))
+		pw.single("gormtool_db_idle_connections", "gauge", "The number of idle connections.", float64(stats.Idle))
+		pw.single("gormtool_db_wait_count_total", "counter", "The total number of connections waited for.", float64(stats.WaitCount))
+		pw.single("gormtool_db_wait_duration_seconds_total", "counter", "The total time blocked waiting for a new connection.", stats.WaitDuration.Seconds())
+		pw.single("gormtool_db_max_idle_closed_total", "counter", "The total number of connections closed due to SetMaxIdleConns.", float64(stats.MaxIdleClosed))
+		pw.single("gormtool_db_max_idle_time_closed_total", "counter", "The total number of connections closed due to SetConnMaxIdleTime.", float64(stats.MaxIdleTimeClosed))
+		pw.single("gormtool_db_max_lifetime_closed_total", "counter", "The total number of connections closed due to SetConnMaxLifetime.", float64(stats.MaxLifetimeClosed))
+	}
+
+	if t.Cache != nil {
+		t.writeCachePrometheus(pw)
+	}
+	return pw.flush()
+}
+
+// writeCachePrometheus 输出 CacheMetrics 和熔断器状态
+func (t *CRUDTool) writeCachePrometheus(pw *promWriter) {
+	cacheMetrics := t.CacheMetrics()
+	tables := make([]string, 0, len(cacheMetrics))
+	for table := range cacheMetrics {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	families := []struct {
+		name, help string
+		value      func(s CacheModelStats) float64
+	}{
+		{"gormtool_cache_hits_total", "Cache hits for records and lists.", func(s CacheModelStats) float64 { return float64(s.Hits) }},
+		{"gormtool_cache_misses_total", "Cache misses for records and lists.", func(s CacheModelStats) float64 { return float64(s.Misses) }},
+		{"gormtool_cache_sets_total", "Cache writes.", func(s CacheModelStats) float64 { return float64(s.Sets) }},
+		{"gormtool_cache_deletes_total", "Cache deletes.", func(s CacheModelStats) float64 { return float64(s.Deletes) }},
+		{"gormtool_cache_errors_total", "Cache call errors (timeouts included).", func(s CacheModelStats) float64 { return float64(s.Errors) }},
+		{"gormtool_cache_operations_total", "Cache calls.", func(s CacheModelStats) float64 { return float64(s.Operations) }},
+		{"gormtool_cache_latency_seconds_total", "Total time spent in cache calls.", func(s CacheModelStats) float64 { return s.TotalLatencyMs / 1000 }},
+	}
+	for _, f := range families {
+		pw.header(f.name, "counter", f.help)
+		for _, table := range tables {
+			pw.sample(f.name, []string{"table"}, []string{table}, f.value(cacheMetrics[table]))
+		}
+	}
+
+	breaker := t.CacheBreakerStats()
+	pw.header("gormtool_cache_breaker_state", "gauge", "Cache circuit breaker state (1 for the current state).")
+	for _, state := range []string{BreakerClosed, BreakerOpen, BreakerHalfOpen} {
+		value := 0.0
+		if breaker.State == state {
+			value = 1
+		}
+		pw.sample("gormtool_cache_breaker_state", []string{"state"}, []string{state}, value)
+	}
+	pw.single("gormtool_cache_breaker_opens_total", "counter", "Times the cache circuit breaker opened.", float64(breaker.Opens))
+	pw.single("gormtool_cache_breaker_skipped_total", "counter", "Cache calls skipped while the circuit breaker was open.", float64(breaker.Skipped))
+	pw.single("gormtool_cache_timeouts_total", "counter", "Cache calls that exceeded CacheTimeout.", float64(breaker.Timeouts))
+}
+
+// promWriter 文本格式输出，记录第一个写入错误
+type promWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (pw *promWriter) printf(format string, args ...interface{}) {
+	if pw.err == nil {
+		_, pw.err = fmt.Fprintf(pw.w, format, args...)
+	}
+}
+
+func (pw *promWriter) flush() error {
+	if pw.err != nil {
+		return pw.err
+	}
+	return pw.w.Flush()
+}
+
+func (pw *promWriter) header(name, typ, help string) {
+	pw.printf("# HELP %s %s\n# TYPE %s %s\n", name, helpReplacer.Replace(help), name, typ)
+}
+
+func (pw *promWriter) sample(name string, labels, values []string, v float64) {
+	pw.printf("%s%s %s\n", name, formatLabels(labels, values), formatFloat(v))
+}
+
+func (pw *promWriter) single(name, typ, help string, v float64) {
+	pw.header(name, typ, help)
+	pw.sample(name, nil, nil, v)
+}
+
+func (pw *promWriter) counterVec(name, help string, labels []string, c *promCounter) {
+	pw.header(name, "counter", help)
+	for _, s := range c.snapshot() {
+		pw.sample(name, labels, s.labelValues, s.value)
+	}
+}
+
+func (pw *promWriter) histogramVec(name, help string, labels []string, h *promHistogram) {
+	pw.header(name, "histogram", help)
+	buckets, series := h.snapshot()
+	bucketLabels := append(append([]string(nil), labels...), "le")
+	for _, s := range series {
+		var cumulative uint64
+		for i, upper := range buckets {
+			cumulative += s.counts[i]
+			pw.sample(name+"_bucket", bucketLabels, append(append([]string(nil), s.labelValues...), formatFloat(upper)), float64(cumulative))
+		}
+		pw.sample(name+"_bucket", bucketLabels, append(append([]string(nil), s.labelValues...), "+Inf"), float64(s.count))
+		pw.sample(name+"_sum", labels, s.labelValues, s.sum)
+		pw.sample(name+"_count", labels, s.labelValues, float64(s.count))
+	}
+}
+
+// formatLabels 格式化标签，值中的反斜杠、双引号和换行需要转义
+func formatLabels(labels, values []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, label := range labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(label)
+		b.WriteString(`="`)
+		b.WriteString(labelValueReplacer.Replace(values[i]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// helpReplacer HELP 文本中只需要转义反斜杠和换行
+var helpReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,138 @@
+// gormtool\metrics_prometheus_test.go
+package gormtool
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 文本格式的 golden 测试：HELP/TYPE 行、标签值转义、累计的直方图桶、_sum 和 _count
+func TestPromWriterGolden(t *testing.T) {
+	var counter promCounter
+	counter.add(1, "get", `a"b\c`+"\nd")
+	counter.add(2, "create", "users")
+	counter.add(0.5, "create", "users")
+
+	hist := promHistogram{buckets: []float64{0.1, 1}}
+	hist.observe(0.05, "get")
+	hist.observe(0.1, "get") // 等于上界的值计入该桶
+	hist.observe(0.5, "get")
+	hist.observe(3, "get") // 超过所有上界只计入 +Inf
+
+	var buf bytes.Buffer
+	pw := &promWriter{w: bufio.NewWriter(&buf)}
+	pw.counterVec("test_ops_total", "Ops with a \\ and\na newline.", []string{"op", "model"}, &counter)
+	pw.histogramVec("test_duration_seconds", "Latency.", []string{"op"}, &hist)
+	pw.single("test_in_flight", "gauge", "In flight.", 3)
+	if err := pw.flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	const want = `# HELP test_ops_total Ops with a \\ and\na newline.
+# TYPE test_ops_total counter
+test_ops_total{op="create",model="users"} 2.5
+test_ops_total{op="get",model="a\"b\\c\nd"} 1
+# HELP test_duration_seconds Latency.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{op="get",le="0.1"} 2
+test_duration_seconds_bucket{op="get",le="1"} 3
+test_duration_seconds_bucket{op="get",le="+Inf"} 4
+test_duration_seconds_sum{op="get"} 3.65
+test_duration_seconds_count{op="get"} 4
+# HELP test_in_flight In flight.
+# TYPE test_in_flight gauge
+test_in_flight 3
+`
+	if got := buf.String(); got != want {
+		t.Fatalf("输出不一致\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// WritePrometheus 的完整输出：每个指标族先有 HELP 和 TYPE，族名不重复，样本属于刚声明的族
+func TestWritePrometheusFormat(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	items := env.seed(t, "a")
+	var item testItem
+	env.tool.FindByID(ctx, &item, items[0].ID, FindOptions{})
+	env.tool.FindByID(ctx, &item, 999, FindOptions{})
+	env.tool.LogOperation(ctx, "list", &testItem{}, time.Millisecond, errors.New("boom"), nil)
+
+	r := newTestRouter()
+	r.Use(env.tool.MetricsMiddleware())
+	r.GET("/metrics", env.tool.PrometheusMetrics)
+	r.GET("/items/:id", func(c *gin.Context) { env.tool.GetByID(c, &testItem{}) })
+	doRequest(t, r, http.MethodGet, "/items/1", "")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("GET /metrics = %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	families := map[string]string{}
+	var family, typ string
+	for i, line := range strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case strings.HasPrefix(line, "# HELP "):
+			family, typ = fields[2], ""
+			if _, dup := families[family]; dup {
+				t.Fatalf("第 %d 行: 指标族 %s 重复", i+1, family)
+			}
+			families[family] = ""
+		case strings.HasPrefix(line, "# TYPE "):
+			if fields[2] != family || len(fields) != 4 {
+				t.Fatalf("第 %d 行: TYPE 与 HELP 不匹配: %q", i+1, line)
+			}
+			typ = fields[3]
+			families[family] = typ
+		default:
+			if typ == "" || len(fields) < 2 {
+				t.Fatalf("第 %d 行: 样本前缺少 HELP/TYPE: %q", i+1, line)
+			}
+			name := line[:strings.IndexAny(line, "{ ")]
+			ok := name == family
+			if typ == "histogram" {
+				ok = name == family+"_bucket" || name == family+"_sum" || name == family+"_count"
+			}
+			if !ok {
+				t.Fatalf("第 %d 行: 样本 %s 不属于指标族 %s", i+1, name, family)
+			}
+		}
+	}
+
+	body := w.Body.String()
+	for _, sample := range []string{
+		`gormtool_operations_total{operation="get_by_id",model="test_items",status="success"} 2`,
+		`gormtool_errors_total{type="not_found",operation="get_by_id"} 1`,
+		`gormtool_errors_total{type="db_error",operation="list"} 1`,
+		`gormtool_http_requests_total{method="GET",route="/items/:id",status="200"} 1`,
+		`gormtool_http_request_duration_seconds_count{method="GET",route="/items/:id"} 1`,
+		`gormtool_cache_hits_total{table="test_items"} 1`,
+		`gormtool_cache_breaker_state{state="closed"} 1`,
+	} {
+		if !strings.Contains(body, sample+"\n") {
+			t.Errorf("缺少样本 %s", sample)
+		}
+	}
+	for name, want := range map[string]string{
+		"gormtool_operation_duration_seconds": "histogram",
+		"gormtool_http_requests_in_flight":    "gauge",
+		"gormtool_db_open_connections":        "gauge",
+		"gormtool_cache_misses_total":         "counter",
+	} {
+		if families[name] != want {
+			t.Errorf("%s 类型 = %q, want %s", name, families[name], want)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+// gormtool\middleware.go
+package gormtool
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 访问日志和 panic 恢复中间件，替代 gin.Default() 中输出文本的 Logger 和 Recovery
+// 推荐顺序：
+//
+//	r := gin.New()
+//	r.Use(gormtool.RequestIDMiddleware())
+//	r.Use(cruder.AccessLogMiddleware())
+//	r.Use(cruder.TracingMiddleware(), cruder.MetricsMiddleware())
+//	r.Use(cruder.RecoveryMiddleware()) // 放在追踪和指标之后，panic 的请求也按 500 记录
+
+// AccessLogMiddleware 每个请求结束后通过 Logger 输出一条访问日志，
+// 包含 method、route（路由模板）、path、status、latency、bytes、request_id 和 user_id；
+// 5xx 为 Error 级别，4xx 为 Warn 级别，其余为 Info 级别。skipPaths 中的路径（如 /metrics）不记录
+func (t *CRUDTool) AccessLogMiddleware(skipPaths ...string) gin.HandlerFunc {
+	skip := make(map[string]bool, len(skipPaths))
+	for _, p := range skipPaths {
+		skip[p] = true
+	}
+	return func(c *gin.Context) {
+		start := time.Now()
+		path := c.Request.URL.Path
+		c.Next()
+
+		if skip[path] {
+			return
+		}
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := c.Writer.Status()
+		fields := map[string]interface{}{
+			"method":     c.Request.Method,
+			"route":      route,
+			"path":       path,
+			"status":     status,
+			"latency":    time.Since(start).String(),
+			"bytes":      max(c.Writer.Size(), 0),
+			"client_ip":  c.ClientIP(),
+			"user_agent": c.Request.UserAgent(),
+		}
+		if id := requestID(c); id != "" {
+			fields["request_id"] = id
+		}
+		// 在 c.Next() 之后读取，此时认证中间件已经设置了 UserIDKey
+		if userID, ok := c.Get(UserIDKey); ok {
+			fields["user_id"] = userID
+		}
+		if len(c.Errors) > 0 {
+			fields["errors"] = c.Errors.String()
+		}
+
+		ctx := c.Request.Context()
+		switch {
+		case status >= http.StatusInternalServerError:
+			t.Logger.Error(ctx, "HTTP 请求", fields)
+		case status >= http.StatusBadRequest:
+			t.Logger.Warn(ctx, "HTTP 请求", fields)
+		default:
+			t.Logger.Info(ctx, "HTTP 请求", fields)
+		}
+	}
+}
+
+// RecoveryMiddleware 恢复处理函数中的 panic：记录 panic 值和调用栈，
+// 返回 500 和 error_code 为 internal_error 的 Response（不暴露 panic 内容）
+// 客户端已断开连接（broken pipe）时只记录日志，不再写响应
+func (t *CRUDTool) RecoveryMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			if r == http.ErrAbortHandler {
+				// 处理函数主动中止响应，交给 net/http 处理
+				panic(r)
+			}
+			ctx := c.Request.Context()
+			err, ok := r.(error)
+			if !ok {
+				err = fmt.Errorf("%v", r)
+			}
+			fields := map[string]interface{}{
+				"panic":  fmt.Sprint(r),
+				"method": c.Request.Method,
+				"path":   c.Request.URL.Path,
+			}
+
+			span := trace.SpanFromContext(ctx)
+			span.RecordError(err, trace.WithStackTrace(true))
+			span.SetStatus(codes.Error, "panic")
+
+			if brokenPipe(err) {
+				t.Logger.Warn(ctx, "客户端已断开连接", fields)
+				c.Error(err)
+				c.Abort()
+				return
+			}
+
+			fields["stack"] = string(debug.Stack())
+			t.Logger.Error(ctx, "请求处理发生 panic", fields)
+			c.Error(err)
+			if c.Writer.Written() {
+				// 响应已经开始写入，无法再返回 JSON
+				c.Abort()
+				return
+			}
+			RespondError(c, NewError(ErrKindInternal, "panic", "服务器内部错误", err))
+			c.Abort()
+		}()
+		c.Next()
+	}
+}
+
+// brokenPipe 判断是否为客户端断开连接导致的写入错误
+func brokenPipe(err error) bool {
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		return false
+	}
+	var sysErr *os.SyscallError
+	if errors.As(opErr, &sysErr) {
+		if errors.Is(sysErr.Err, syscall.EPIPE) || errors.Is(sysErr.Err, syscall.ECONNRESET) {
+			return true
+		}
+	}
+	msg := strings.ToLower(opErr.Error())
+	return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+}
@@ -0,0 +1,178 @@
+// gormtool\middleware_test.go
+package gormtool
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// logEntries 解析 JSON 日志，每行一条
+func logEntries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("解析日志失败: %v\n%s", err, line)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// middlewareTool 返回日志写入 buf 的 CRUDTool
+func middlewareTool(buf *bytes.Buffer) *CRUDTool {
+	return NewCRUDTool(nil, nil, NewSlogLogger(slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+}
+
+// 访问日志包含请求字段，级别按状态码区分，skipPaths 中的路径不记录
+func TestAccessLogMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	tool := middlewareTool(&buf)
+	r := newTestRouter()
+	r.Use(RequestIDMiddleware(), tool.AccessLogMiddleware("/metrics"))
+	// 认证在访问日志之后运行，访问日志在 c.Next() 之后读取 user_id
+	r.Use(func(c *gin.Context) { c.Set(UserIDKey, 42) })
+	r.GET("/items/:id", func(c *gin.Context) { c.String(http.StatusOK, "hello") })
+	r.GET("/bad", func(c *gin.Context) { c.Status(http.StatusBadRequest) })
+	r.GET("/fail", func(c *gin.Context) {
+		c.Error(errBackend)
+		c.Status(http.StatusInternalServerError)
+	})
+	r.GET("/metrics", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/items/7?x=1", nil)
+	req.Header.Set(RequestIDHeader, "req-access")
+	req.Header.Set("User-Agent", "test-agent")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := logEntries(t, &buf)
+	if len(entries) != 1 {
+		t.Fatalf("日志条数 = %d, want 1: %s", len(entries), buf.String())
+	}
+	entry := entries[0]
+	want := map[string]interface{}{
+		"level":      "INFO",
+		"method":     "GET",
+		"route":      "/items/:id",
+		"path":       "/items/7",
+		"status":     float64(200),
+		"bytes":      float64(5),
+		"request_id": "req-access",
+		"user_id":    float64(42),
+		"user_agent": "test-agent",
+	}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("%s = %v, want %v", k, entry[k], v)
+		}
+	}
+	if latency, _ := entry["latency"].(string); latency == "" {
+		t.Errorf("latency = %v", entry["latency"])
+	}
+
+	for target, level := range map[string]string{"/bad": "WARN", "/fail": "ERROR", "/nope": "WARN"} {
+		buf.Reset()
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+		entries := logEntries(t, &buf)
+		if len(entries) != 1 || entries[0]["level"] != level {
+			t.Errorf("%s 日志 = %v, want level %s", target, entries, level)
+			continue
+		}
+		if target == "/fail" && !strings.Contains(entries[0]["errors"].(string), errBackend.Error()) {
+			t.Errorf("/fail errors = %v", entries[0]["errors"])
+		}
+		if target == "/nope" && entries[0]["route"] != "unmatched" {
+			t.Errorf("未匹配的路由 route = %v", entries[0]["route"])
+		}
+	}
+
+	buf.Reset()
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if buf.Len() != 0 {
+		t.Fatalf("skipPaths 中的路径被记录: %s", buf.String())
+	}
+}
+
+// panic 返回 JSON 的 500 响应（不暴露 panic 内容），记录 panic 值和调用栈，并标记 span
+func TestRecoveryMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	tool := middlewareTool(&buf)
+	sr := useSpanRecorder(t, tool)
+	r := newTestRouter()
+	r.Use(RequestIDMiddleware(), tool.AccessLogMiddleware(), tool.TracingMiddleware(), tool.RecoveryMiddleware())
+	r.GET("/panic", func(c *gin.Context) { panic("db password leaked") })
+	r.GET("/partial", func(c *gin.Context) {
+		c.String(http.StatusOK, "partial")
+		panic("after write")
+	})
+	r.GET("/abort", func(c *gin.Context) { panic(http.ErrAbortHandler) })
+
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	req.Header.Set(RequestIDHeader, "req-panic")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var resp Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("响应不是 JSON: %v\n%s", err, w.Body.String())
+	}
+	if w.Code != http.StatusInternalServerError || resp.Code != http.StatusInternalServerError ||
+		resp.ErrorCode != ErrKindInternal || resp.RequestID != "req-panic" {
+		t.Fatalf("panic 响应 = %d %+v", w.Code, resp)
+	}
+	if strings.Contains(w.Body.String(), "leaked") {
+		t.Fatalf("响应暴露了 panic 内容: %s", w.Body.String())
+	}
+
+	var panicLog, accessLog map[string]interface{}
+	for _, entry := range logEntries(t, &buf) {
+		switch entry["msg"] {
+		case "请求处理发生 panic":
+			panicLog = entry
+		case "HTTP 请求":
+			accessLog = entry
+		}
+	}
+	if panicLog == nil || panicLog["level"] != "ERROR" || panicLog["panic"] != "db password leaked" || panicLog["request_id"] != "req-panic" {
+		t.Fatalf("panic 日志 = %v", panicLog)
+	}
+	if stack, _ := panicLog["stack"].(string); !strings.Contains(stack, "middleware_test.go") {
+		t.Fatalf("调用栈 = %q", stack)
+	}
+	if accessLog == nil || accessLog["status"] != float64(500) || accessLog["level"] != "ERROR" {
+		t.Fatalf("访问日志 = %v", accessLog)
+	}
+	spans := spansByName(sr)
+	if span := spans["GET /panic"]; span == nil || span.Status().Code != codes.Error || len(span.Events()) == 0 {
+		t.Fatalf("请求 span = %+v", span)
+	}
+
+	// 已经开始写响应时不再追加 JSON
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/partial", nil))
+	if w.Body.String() != "partial" {
+		t.Fatalf("已写入的响应被修改: %q", w.Body.String())
+	}
+
+	// http.ErrAbortHandler 继续向上抛出，交给 net/http 处理
+	func() {
+		defer func() {
+			if rec := recover(); rec != http.ErrAbortHandler {
+				t.Fatalf("recover = %v, want http.ErrAbortHandler", rec)
+			}
+		}()
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abort", nil))
+	}()
+}
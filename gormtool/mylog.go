@@ -30,22 +30,24 @@ func NewDefaultLogger() *DefaultLogger {
 }
 
 func (l *DefaultLogger) Debug(ctx context.Context, msg string, fields map[string]interface{}) {
-	l.log("DEBUG", msg, fields)
+	l.log(ctx, "DEBUG", msg, fields)
 }
 
 func (l *DefaultLogger) Info(ctx context.Context, msg string, fields map[string]interface{}) {
-	l.log("INFO", msg, fields)
+	l.log(ctx, "INFO", msg, fields)
 }
 
 func (l *DefaultLogger) Warn(ctx context.Context, msg string, fields map[string]interface{}) {
-	l.log("WARN", msg, fields)
+	l.log(ctx, "WARN", msg, fields)
 }
 
 func (l *DefaultLogger) Error(ctx context.Context, msg string, fields map[string]interface{}) {
-	l.log("ERROR", msg, fields)
+	l.log(ctx, "ERROR", msg, fields)
 }
 
-func (l *DefaultLogger) log(level, msg string, fields map[string]interface{}) {
+// log 输出时合并 ctx 中的请求字段（request_id 等）
+func (l *DefaultLogger) log(ctx context.Context, level, msg string, fields map[string]interface{}) {
+	fields = mergeContextFields(ctx, fields)
 	logMsg := fmt.Sprintf("[%s] %s", level, msg)
 	if len(fields) > 0 {
 		jsonFields, _ := json.Marshal(fields)
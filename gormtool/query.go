@@ -0,0 +1,481 @@
+// gormtool\query.go
+package gormtool
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// QueryCondition 查询条件结构
+type QueryCondition struct {
+	Field      string      `json:"field"`
+	Operator   string      `json:"operator"` // =, !=, >, <, >=, <=, LIKE, ILIKE, IN, NOT IN, BETWEEN, IS NULL, IS NOT NULL, STARTS WITH, ENDS WITH
+	Value      interface{} `json:"value"`
+	IgnoreCase bool        `json:"ignore_case,omitempty"` // 忽略大小写（对字符串比较生效）
+}
+
+// ConditionGroup 条件组，可递归嵌套
+// Logic 为 and（默认）或 or，Not 为 true 时对整个组取反
+//
+//	{"logic": "or", "conditions": [{"field": "name", "operator": "LIKE", "value": "x"}],
+//	 "groups": [{"not": true, "conditions": [{"field": "age", "operator": ">", "value": 30}]}]}
+type ConditionGroup struct {
+	Logic      string           `json:"logic,omitempty"`
+	Not        bool             `json:"not,omitempty"`
+	Conditions []QueryCondition `json:"conditions,omitempty"`
+	Groups     []ConditionGroup `json:"groups,omitempty"`
+}
+
+// SortCondition 排序条件
+type SortCondition struct {
+	Field     string `json:"field"`
+	Direction string `json:"direction"` // ASC, DESC
+}
+
+// QueryBuilder 查询构建器
+// Conditions 之间以及与 Where 之间都是 AND 关系
+type QueryBuilder struct {
+	Conditions []QueryCondition `json:"conditions"`
+	Where      *ConditionGroup  `json:"where,omitempty"`
+	Sorts      []SortCondition  `json:"sorts"`
+	Preloads   []string         `json:"preloads"`
+
+	// 稀疏字段：Select 为主模型返回的字段，SelectRelations 为关联名到字段的映射，
+	// 指定了字段的关联会自动预加载；主键和关联所需的外键总是会查询
+	Select          []string            `json:"select,omitempty"`
+	SelectRelations map[string][]string `json:"select_relations,omitempty"`
+
+	// 全文搜索关键词，模型需要先调用 RegisterSearch；没有排序字段时按相关度排序
+	Search string `json:"search,omitempty"`
+}
+
+// maxGroupDepth 条件组最大嵌套层数
+const maxGroupDepth = 8
+
+// FieldPolicy 模型的查询字段策略
+// Allowed 为空时允许模型上所有列；Aliases 把外部字段名映射为结构体字段名或列名
+//
+//	cruder.SetFieldPolicy(&models.User{}, gormtool.FieldPolicy{
+//		Allowed: []string{"name", "age", "created_at"},
+//		Aliases: map[string]string{"createdAt": "created_at"},
+//	})
+type FieldPolicy struct {
+	Allowed []string
+	Aliases map[string]string
+}
+
+// SetFieldPolicy 设置模型的查询字段策略
+func (t *CRUDTool) SetFieldPolicy(model interface{}, policy FieldPolicy) {
+	t.fieldPolicies.Store(modelStructType(model), policy)
+}
+
+// modelStructType 返回模型的结构体类型，支持 *T、[]T、*[]T、*[]*T
+func modelStructType(model interface{}) reflect.Type {
+	typ := reflect.TypeOf(model)
+	for typ != nil && (typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array) {
+		typ = typ.Elem()
+	}
+	return typ
+}
+
+// fieldResolver 把外部字段名解析为模型 schema 上的列
+type fieldResolver struct {
+	schema     *schema.Schema
+	policy     FieldPolicy
+	namer      schema.Namer
+	aggregates map[string]clause.Expression // 聚合别名 -> 聚合表达式，仅在构建 HAVING 时设置
+}
+
+func (t *CRUDTool) newFieldResolver(model interface{}) (*fieldResolver, error) {
+	sch, err := t.ParseSchema(model)
+	if err != nil {
+		return nil, err
+	}
+	return t.resolverFor(sch), nil
+}
+
+// resolverFor 返回指定 schema 的字段解析器，用于关联模型
+func (t *CRUDTool) resolverFor(sch *schema.Schema) *fieldResolver {
+	r := &fieldResolver{schema: sch, namer: t.DB.NamingStrategy}
+	if policy, ok := t.fieldPolicies.Load(sch.ModelType); ok {
+		r.policy = policy.(FieldPolicy)
+	}
+	return r
+}
+
+// resolve 解析字段：别名 -> 结构体字段名/列名 -> 驼峰转列名，并检查白名单
+func (r *fieldResolver) resolve(name string) (*schema.Field, error) {
+	if alias, ok := r.policy.Aliases[name]; ok {
+		name = alias
+	}
+
+	field := r.schema.LookUpField(name)
+	if field == nil && r.namer != nil {
+		field = r.schema.LookUpField(r.namer.ColumnName("", name))
+	}
+	if field == nil || field.DBName == "" {
+		return nil, invalidQuery("无效的查询字段: %s", name)
+	}
+
+	if len(r.policy.Allowed) > 0 {
+		allowed := false
+		for _, a := range r.policy.Allowed {
+			if a == field.DBName || a == field.Name {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return nil, invalidQuery("不允许查询的字段: %s", name)
+		}
+	}
+	return field, nil
+}
+
+// operand 返回条件左侧的表达式：聚合别名（HAVING 中）或模型列，聚合别名没有对应的 schema 字段
+func (r *fieldResolver) operand(name string) (interface{}, *schema.Field, error) {
+	if expr, ok := r.aggregates[name]; ok {
+		return expr, nil, nil
+	}
+	field, err := r.resolve(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return clause.Column{Table: clause.CurrentTable, Name: field.DBName}, field, nil
+}
+
+// column 返回带表名、会被正确转义的列
+func (r *fieldResolver) column(name string) (clause.Column, error) {
+	field, err := r.resolve(name)
+	if err != nil {
+		return clause.Column{}, err
+	}
+	return clause.Column{Table: clause.CurrentTable, Name: field.DBName}, nil
+}
+
+// invalidQuery 查询参数错误，映射为 400
+func invalidQuery(format string, args ...interface{}) error {
+	return NewError(ErrKindInvalidArgument, "build_query", fmt.Sprintf(format, args...), nil)
+}
+
+// buildCondition 把单个查询条件转换为 SQL 表达式
+func (r *fieldResolver) buildCondition(cond QueryCondition) (clause.Expression, error) {
+	col, field, err := r.operand(cond.Field)
+	if err != nil {
+		return nil, err
+	}
+
+	op := strings.ToUpper(strings.Join(strings.Fields(cond.Operator), " "))
+	ignoreCase := cond.IgnoreCase
+	if op == "ILIKE" {
+		op, ignoreCase = "LIKE", true
+	}
+
+	// 忽略大小写时两边都转为小写
+	var target interface{} = col
+	if ignoreCase {
+		target = clause.Expr{SQL: "LOWER(?)", Vars: []interface{}{col}}
+	}
+
+	switch op {
+	case "=", "!=", ">", "<", ">=", "<=":
+		value, err := coerceValue(field, cond.Value)
+		if err != nil {
+			return nil, err
+		}
+		return clause.Expr{SQL: "? " + op + " ?", Vars: []interface{}{target, lowerIf(ignoreCase, value)}}, nil
+	case "LIKE", "STARTS WITH", "ENDS WITH":
+		s, ok := cond.Value.(string)
+		if !ok {
+			return nil, invalidQuery("%s 的值必须是字符串: %s", op, cond.Field)
+		}
+		s = lowerIf(ignoreCase, s).(string)
+		switch op {
+		case "LIKE":
+			return clause.Expr{SQL: "? LIKE ?", Vars: []interface{}{target, "%" + s + "%"}}, nil
+		case "STARTS WITH":
+			return likeExpr(target, escapeLike(s)+"%"), nil
+		default:
+			return likeExpr(target, "%"+escapeLike(s)), nil
+		}
+	case "IN", "NOT IN":
+		values, err := coerceValues(field, cond.Value)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) == 0 {
+			return nil, invalidQuery("%s 的值必须是非空数组: %s", op, cond.Field)
+		}
+		for i, v := range values {
+			values[i] = lowerIf(ignoreCase, v)
+		}
+		return clause.Expr{SQL: "? " + op + " ?", Vars: []interface{}{target, values}}, nil
+	case "BETWEEN":
+		values, err := coerceValues(field, cond.Value)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) != 2 {
+			return nil, invalidQuery("BETWEEN 的值必须是两个元素的数组: %s", cond.Field)
+		}
+		return clause.Expr{SQL: "? BETWEEN ? AND ?", Vars: []interface{}{target, lowerIf(ignoreCase, values[0]), lowerIf(ignoreCase, values[1])}}, nil
+	case "IS NULL", "IS NOT NULL":
+		return clause.Expr{SQL: "? " + op, Vars: []interface{}{col}}, nil
+	default:
+		return nil, invalidQuery("不支持的操作符: %s", cond.Operator)
+	}
+}
+
+// buildGroup 递归构建条件组
+func (r *fieldResolver) buildGroup(group *ConditionGroup, depth int) (clause.Expression, error) {
+	if depth > maxGroupDepth {
+		return nil, invalidQuery("条件组嵌套超过 %d 层", maxGroupDepth)
+	}
+
+	var logic string
+	switch strings.ToUpper(strings.TrimSpace(group.Logic)) {
+	case "", "AND":
+		logic = "AND"
+	case "OR":
+		logic = "OR"
+	default:
+		return nil, invalidQuery("无效的条件组逻辑: %s", group.Logic)
+	}
+
+	exprs := make([]clause.Expression, 0, len(group.Conditions)+len(group.Groups))
+	for _, cond := range group.Conditions {
+		expr, err := r.buildCondition(cond)
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+	}
+	for i := range group.Groups {
+		expr, err := r.buildGroup(&group.Groups[i], depth+1)
+		if err != nil {
+			return nil, err
+		}
+		if expr != nil {
+			exprs = append(exprs, expr)
+		}
+	}
+
+	if len(exprs) == 0 {
+		return nil, nil
+	}
+	return groupExpr{logic: logic, not: group.Not, exprs: exprs}, nil
+}
+
+// groupExpr 用 AND/OR 连接多个表达式并加括号，可整体取反
+type groupExpr struct {
+	logic string
+	not   bool
+	exprs []clause.Expression
+}
+
+func (g groupExpr) Build(builder clause.Builder) {
+	if g.not {
+		builder.WriteString("NOT ")
+	}
+	builder.WriteByte('(')
+	for i, expr := range g.exprs {
+		if i > 0 {
+			builder.WriteString(" " + g.logic + " ")
+		}
+		expr.Build(builder)
+	}
+	builder.WriteByte(')')
+}
+
+// lowerIf 忽略大小写时把字符串转为小写
+func lowerIf(ignoreCase bool, value interface{}) interface{} {
+	if s, ok := value.(string); ok && ignoreCase {
+		return strings.ToLower(s)
+	}
+	return value
+}
+
+// likeEscapeChar LIKE 的转义字符
+// 不使用反斜杠：MySQL 默认的 sql_mode 下 '\' 中的反斜杠会转义结束引号，造成语法错误
+const likeEscapeChar = "!"
+
+// escapeLike 转义 LIKE 通配符，与 likeExpr 配合使用
+func escapeLike(s string) string {
+	return strings.NewReplacer(likeEscapeChar, likeEscapeChar+likeEscapeChar, "%", likeEscapeChar+"%", "_", likeEscapeChar+"_").Replace(s)
+}
+
+// likeExpr 生成 target LIKE pattern，pattern 中的字面量部分需经过 escapeLike
+func likeExpr(target interface{}, pattern string) clause.Expression {
+	return clause.Expr{SQL: "? LIKE ? ESCAPE '" + likeEscapeChar + "'", Vars: []interface{}{target, pattern}}
+}
+
+// buildSort 把排序条件转换为 ORDER BY 列
+func (r *fieldResolver) buildSort(sort SortCondition) (clause.OrderByColumn, error) {
+	col, err := r.column(sort.Field)
+	if err != nil {
+		return clause.OrderByColumn{}, err
+	}
+	desc, err := sortDesc(sort.Direction)
+	if err != nil {
+		return clause.OrderByColumn{}, err
+	}
+	return clause.OrderByColumn{Column: col, Desc: desc}, nil
+}
+
+// sortDesc 解析排序方向，空值为升序
+func sortDesc(direction string) (bool, error) {
+	switch strings.ToUpper(strings.TrimSpace(direction)) {
+	case "", "ASC":
+		return false, nil
+	case "DESC":
+		return true, nil
+	default:
+		return false, invalidQuery("无效的排序方向: %s", direction)
+	}
+}
+
+// checkPreload 检查预加载路径（如 Tags 或 Orders.Items）在模型上存在，返回最后一级关联
+func (r *fieldResolver) checkPreload(preload string) (*schema.Relationship, error) {
+	sch := r.schema
+	var rel *schema.Relationship
+	for _, name := range strings.Split(preload, ".") {
+		var ok bool
+		rel, ok = sch.Relationships.Relations[name]
+		if !ok {
+			return nil, invalidQuery("无效的预加载关联: %s", preload)
+		}
+		sch = rel.FieldSchema
+	}
+	return rel, nil
+}
+
+// coerceValue 把字符串值转换为字段的类型（URL 参数都是字符串），其他类型原样返回
+func coerceValue(field *schema.Field, value interface{}) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok {
+		return value, nil
+	}
+
+	// 聚合别名没有字段类型，数字形式的字符串按数字比较
+	if field == nil {
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f, nil
+		}
+		return value, nil
+	}
+
+	var (
+		v   interface{}
+		err error
+	)
+	switch field.DataType {
+	case schema.Int:
+		v, err = strconv.ParseInt(s, 10, 64)
+	case schema.Uint:
+		v, err = strconv.ParseUint(s, 10, 64)
+	case schema.Float:
+		v, err = strconv.ParseFloat(s, 64)
+	case schema.Bool:
+		v, err = strconv.ParseBool(s)
+	default:
+		return value, nil
+	}
+	if err != nil {
+		return nil, invalidQuery("字段 %s 的值无效: %s", field.DBName, s)
+	}
+	return v, nil
+}
+
+// coerceValues 把数组值逐个转换为字段的类型
+func coerceValues(field *schema.Field, value interface{}) ([]interface{}, error) {
+	values, ok := toSlice(value)
+	if !ok {
+		return nil, invalidQuery("条件的值必须是数组")
+	}
+	coerced := make([]interface{}, len(values))
+	for i, v := range values {
+		c, err := coerceValue(field, v)
+		if err != nil {
+			return nil, err
+		}
+		coerced[i] = c
+	}
+	return coerced, nil
+}
+
+// toSlice 把 []T 转换为 []interface{}
+func toSlice(value interface{}) ([]interface{}, bool) {
+	if values, ok := value.([]interface{}); ok {
+		return values, true
+	}
+	rv := reflect.ValueOf(value)
+	if !rv.IsValid() || (rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array) {
+		return nil, false
+	}
+	values := make([]interface{}, rv.Len())
+	for i := range values {
+		values[i] = rv.Index(i).Interface()
+	}
+	return values, true
+}
+
+// BuildQuery 查询构建器方法
+// 字段按 model 的 GORM schema 解析并转义，未知字段、操作符、排序方向返回 ErrKindInvalidArgument
+func (t *CRUDTool) BuildQuery(db *gorm.DB, model interface{}, qb *QueryBuilder) (*gorm.DB, error) {
+	if qb == nil {
+		return db, nil
+	}
+
+	r, err := t.newFieldResolver(model)
+	if err != nil {
+		return nil, err
+	}
+
+	// 构建条件
+	for _, cond := range qb.Conditions {
+		expr, err := r.buildCondition(cond)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where(expr)
+	}
+
+	// 构建条件组
+	if qb.Where != nil {
+		expr, err := r.buildGroup(qb.Where, 1)
+		if err != nil {
+			return nil, err
+		}
+		if expr != nil {
+			db = db.Where(expr)
+		}
+	}
+
+	// 全文搜索
+	if qb.Search != "" {
+		if db, err = t.applySearch(db, model, qb.Search, len(qb.Sorts) == 0); err != nil {
+			return nil, err
+		}
+	}
+
+	// 构建排序
+	for _, sort := range qb.Sorts {
+		order, err := r.buildSort(sort)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Order(order)
+	}
+
+	// 构建稀疏字段和预加载
+	return t.applySelectAndPreloads(db, r, qb)
+}
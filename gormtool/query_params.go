@@ -0,0 +1,246 @@
+// gormtool\query_params.go
+package gormtool
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// URL 查询参数过滤语法
+//
+//	?filter[age][gte]=18&filter[name][like]=li&filter[id][in]=1,2,3
+//	&sort=-created_at,name
+//	&include=Tags
+//	&fields=id,name&fields[Tags]=id,name
+//	&search=关键词
+//
+// filter[字段]=值 等价于 filter[字段][eq]=值；sort 中 - 前缀表示降序；
+// in/nin 用逗号分隔多个值，between 用逗号分隔两个值；
+// filter[字段][null]=true 表示 IS NULL，=false 表示 IS NOT NULL；
+// fields 为稀疏字段，见 fields.go；search 为全文搜索，见 search.go
+
+// queryParamOperators URL 操作符到 QueryCondition.Operator 的映射
+var queryParamOperators = map[string]string{
+	"eq":      "=",
+	"ne":      "!=",
+	"gt":      ">",
+	"gte":     ">=",
+	"lt":      "<",
+	"lte":     "<=",
+	"like":    "LIKE",
+	"ilike":   "ILIKE",
+	"starts":  "STARTS WITH",
+	"ends":    "ENDS WITH",
+	"null":    "IS NULL",
+	"in":      "IN",
+	"nin":     "NOT IN",
+	"between": "BETWEEN",
+}
+
+// ParamError 查询参数错误详情
+type ParamError struct {
+	Param  string `json:"param"`
+	Value  string `json:"value,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// ParseQueryParams 把 URL 查询参数解析为 QueryBuilder
+// 语法错误汇总为一个 ErrKindInvalidArgument 错误，Details 为 []ParamError
+func ParseQueryParams(values url.Values) (*QueryBuilder, error) {
+	qb := &QueryBuilder{}
+	var problems []ParamError
+
+	// 按参数名排序，保证生成的条件顺序稳定
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		switch {
+		case key == "sort":
+			for _, value := range values[key] {
+				for _, item := range splitList(value) {
+					direction := "ASC"
+					if strings.HasPrefix(item, "-") {
+						direction = "DESC"
+						item = item[1:]
+					}
+					if item == "" {
+						problems = append(problems, ParamError{Param: key, Value: value, Reason: "排序字段不能为空"})
+						continue
+					}
+					qb.Sorts = append(qb.Sorts, SortCondition{Field: item, Direction: direction})
+				}
+			}
+		case key == "search":
+			qb.Search = strings.TrimSpace(values.Get(key))
+		case key == "include":
+			for _, value := range values[key] {
+				qb.Preloads = append(qb.Preloads, splitList(value)...)
+			}
+		}
+	}
+
+	qb.Select, qb.SelectRelations = parseFieldsParams(values)
+
+	conditions, filterProblems := parseFilterParams(values, "filter")
+	qb.Conditions = append(qb.Conditions, conditions...)
+	problems = append(problems, filterProblems...)
+
+	if len(problems) > 0 {
+		return nil, &Error{
+			Kind:    ErrKindInvalidArgument,
+			Op:      "parse_query_params",
+			Message: "查询参数错误",
+			Details: problems,
+		}
+	}
+	return qb, nil
+}
+
+// filterPatterns 按前缀缓存的参数名正则 map[string]*regexp.Regexp
+var filterPatterns sync.Map
+
+// filterPattern 返回匹配 prefix[字段] 和 prefix[字段][操作符] 的正则
+func filterPattern(prefix string) *regexp.Regexp {
+	if p, ok := filterPatterns.Load(prefix); ok {
+		return p.(*regexp.Regexp)
+	}
+	p := regexp.MustCompile(`^` + regexp.QuoteMeta(prefix) + `\[([^\[\]]+)\](?:\[([^\[\]]+)\])?$`)
+	filterPatterns.Store(prefix, p)
+	return p
+}
+
+// parseFilterParams 解析 prefix[字段][操作符]=值 形式的参数，只处理以 prefix[ 开头的参数
+func parseFilterParams(values url.Values, prefix string) ([]QueryCondition, []ParamError) {
+	pattern := filterPattern(prefix)
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		if strings.HasPrefix(key, prefix+"[") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var (
+		conditions []QueryCondition
+		problems   []ParamError
+	)
+	for _, key := range keys {
+		m := pattern.FindStringSubmatch(key)
+		if m == nil {
+			problems = append(problems, ParamError{Param: key, Reason: "参数格式应为 " + prefix + "[字段][操作符]"})
+			continue
+		}
+		field, op := m[1], m[2]
+		if op == "" {
+			op = "eq"
+		}
+		operator, ok := queryParamOperators[strings.ToLower(op)]
+		if !ok {
+			problems = append(problems, ParamError{Param: key, Reason: "不支持的操作符: " + op})
+			continue
+		}
+		for _, value := range values[key] {
+			cond, reason := filterCondition(field, operator, value)
+			if reason != "" {
+				problems = append(problems, ParamError{Param: key, Value: value, Reason: reason})
+				continue
+			}
+			conditions = append(conditions, cond)
+		}
+	}
+	return conditions, problems
+}
+
+// filterCondition 根据操作符处理过滤值，返回错误原因
+func filterCondition(field, operator, value string) (QueryCondition, string) {
+	cond := QueryCondition{Field: field, Operator: operator}
+	switch operator {
+	case "IN", "NOT IN":
+		items := splitList(value)
+		if len(items) == 0 {
+			return cond, "值不能为空"
+		}
+		cond.Value = toInterfaces(items)
+	case "BETWEEN":
+		items := splitList(value)
+		if len(items) != 2 {
+			return cond, "between 需要逗号分隔的两个值"
+		}
+		cond.Value = toInterfaces(items)
+	case "IS NULL":
+		isNull, err := strconv.ParseBool(value)
+		if err != nil {
+			return cond, "null 的值必须是 true 或 false"
+		}
+		if !isNull {
+			cond.Operator = "IS NOT NULL"
+		}
+	default:
+		cond.Value = value
+	}
+	return cond, ""
+}
+
+// splitList 按逗号分隔并去掉空白项
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func toInterfaces(items []string) []interface{} {
+	values := make([]interface{}, len(items))
+	for i, item := range items {
+		values[i] = item
+	}
+	return values
+}
+
+// Merge 返回合并后的新 QueryBuilder，other 的条件、排序、预加载、稀疏字段追加在后面，不修改原对象
+// 两边都有条件组时合并为一个 AND 组
+func (qb *QueryBuilder) Merge(other *QueryBuilder) *QueryBuilder {
+	merged := &QueryBuilder{}
+	var groups []ConditionGroup
+	for _, b := range []*QueryBuilder{qb, other} {
+		if b == nil {
+			continue
+		}
+		merged.Conditions = append(merged.Conditions, b.Conditions...)
+		merged.Sorts = append(merged.Sorts, b.Sorts...)
+		merged.Preloads = append(merged.Preloads, b.Preloads...)
+		merged.Select = append(merged.Select, b.Select...)
+		for name, fields := range b.SelectRelations {
+			if merged.SelectRelations == nil {
+				merged.SelectRelations = map[string][]string{}
+			}
+			merged.SelectRelations[name] = append(merged.SelectRelations[name], fields...)
+		}
+		if b.Search != "" {
+			merged.Search = b.Search
+		}
+		if b.Where != nil {
+			groups = append(groups, *b.Where)
+		}
+	}
+
+	switch len(groups) {
+	case 1:
+		merged.Where = &groups[0]
+	case 2:
+		merged.Where = &ConditionGroup{Groups: groups}
+	}
+	return merged
+}
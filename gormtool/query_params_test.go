@@ -0,0 +1,120 @@
+// gormtool\query_params_test.go
+package gormtool
+
+import (
+	"context"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParseQueryParams(t *testing.T) {
+	qb, err := ParseQueryParams(url.Values{
+		"sort":                   {"-created_at, name"},
+		"include":                {"Tags,Profile"},
+		"search":                 {" 关键词 "},
+		"filter[name]":           {"li"},
+		"filter[age][gte]":       {"18"},
+		"filter[id][in]":         {"1, 2,3"},
+		"filter[score][between]": {"1,5"},
+		"filter[email][null]":    {"false"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantSorts := []SortCondition{{Field: "created_at", Direction: "DESC"}, {Field: "name", Direction: "ASC"}}
+	if !reflect.DeepEqual(qb.Sorts, wantSorts) {
+		t.Errorf("Sorts = %+v, want %+v", qb.Sorts, wantSorts)
+	}
+	if want := []string{"Tags", "Profile"}; !reflect.DeepEqual(qb.Preloads, want) {
+		t.Errorf("Preloads = %v, want %v", qb.Preloads, want)
+	}
+	if qb.Search != "关键词" {
+		t.Errorf("Search = %q", qb.Search)
+	}
+	// 按参数名排序
+	wantConds := []QueryCondition{
+		{Field: "age", Operator: ">=", Value: "18"},
+		{Field: "email", Operator: "IS NOT NULL"},
+		{Field: "id", Operator: "IN", Value: []interface{}{"1", "2", "3"}},
+		{Field: "name", Operator: "=", Value: "li"},
+		{Field: "score", Operator: "BETWEEN", Value: []interface{}{"1", "5"}},
+	}
+	if !reflect.DeepEqual(qb.Conditions, wantConds) {
+		t.Errorf("Conditions = %+v, want %+v", qb.Conditions, wantConds)
+	}
+}
+
+// 名称以 filter 开头的其他参数不作为过滤条件
+func TestParseQueryParamsIgnoresUnrelated(t *testing.T) {
+	qb, err := ParseQueryParams(url.Values{
+		"filters":      {"x"},
+		"filter_mode":  {"strict"},
+		"filterx[a]":   {"1"},
+		"filter[name]": {"li"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []QueryCondition{{Field: "name", Operator: "=", Value: "li"}}; !reflect.DeepEqual(qb.Conditions, want) {
+		t.Fatalf("Conditions = %+v, want %+v", qb.Conditions, want)
+	}
+	if filterPattern("filter") != filterPattern("filter") {
+		t.Fatal("同一前缀的正则没有复用")
+	}
+}
+
+// 所有参数错误汇总在 Details 中
+func TestParseQueryParamsErrors(t *testing.T) {
+	_, err := ParseQueryParams(url.Values{
+		"sort":                {"-"},
+		"filter[age][approx]": {"18"},
+		"filter[id][between]": {"1"},
+		"filter[email][null]": {"maybe"},
+		"filter[id][in]":      {" , "},
+		"filter[a][b][c]":     {"x"},
+		"filter[name]":        {"ok"},
+	})
+	e, ok := err.(*Error)
+	if !ok || e.Kind != ErrKindInvalidArgument {
+		t.Fatalf("err = %v, want invalid argument", err)
+	}
+	details, _ := e.Details.([]ParamError)
+	params := make(map[string]bool)
+	for _, d := range details {
+		params[d.Param] = true
+	}
+	for _, want := range []string{"sort", "filter[age][approx]", "filter[id][between]", "filter[email][null]", "filter[id][in]", "filter[a][b][c]"} {
+		if !params[want] {
+			t.Errorf("Details 缺少 %s: %+v", want, details)
+		}
+	}
+	if params["filter[name]"] || len(details) != 6 {
+		t.Errorf("Details = %+v, want 6 条", details)
+	}
+}
+
+// 解析结果经过字段校验后用于查询
+func TestParseQueryParamsQuery(t *testing.T) {
+	env := newTestEnv(t)
+	env.seed(t, "a", "b", "c", "d")
+
+	qb, err := ParseQueryParams(url.Values{"filter[score][gte]": {"2"}, "sort": {"-score"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var items []testItem
+	p, err := env.tool.FindPage(context.Background(), &items, qb, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Total != 3 || len(items) != 3 || items[0].Name != "d" || items[2].Name != "b" {
+		t.Fatalf("FindPage = %+v, %+v", p, items)
+	}
+
+	qb, _ = ParseQueryParams(url.Values{"filter[password][eq]": {"x"}})
+	if _, err := env.tool.FindPage(context.Background(), &items, qb, 1, 10); KindOf(err) != ErrKindInvalidArgument {
+		t.Fatalf("未知字段 err = %v, want invalid argument", err)
+	}
+}
@@ -0,0 +1,241 @@
+// gormtool\query_test.go
+package gormtool
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// dryRunSQL 用 DryRun 生成查询 testItem 的 SQL 和参数，不执行
+func dryRunSQL(t *testing.T, tool *CRUDTool, qb *QueryBuilder) (string, []interface{}, error) {
+	t.Helper()
+	db := tool.DB.Session(&gorm.Session{DryRun: true})
+	var items []testItem
+	q, err := tool.BuildQuery(db, &items, qb)
+	if err != nil {
+		return "", nil, err
+	}
+	stmt := q.Find(&items).Statement
+	return stmt.SQL.String(), stmt.Vars, nil
+}
+
+// 字段名和排序方向只能是模型上的列和 ASC/DESC，无效值返回参数错误而不是拼接进 SQL
+func TestBuildQueryRejectsInvalidFields(t *testing.T) {
+	env := newTestEnv(t)
+	fields := []string{
+		"name; DROP TABLE test_items",
+		`"name"--`,
+		"name--",
+		"a.b",
+		"test_items.name",
+		"",
+		"Items",
+		"1=1 OR name",
+	}
+	for _, field := range fields {
+		for name, qb := range map[string]*QueryBuilder{
+			"condition": {Conditions: []QueryCondition{{Field: field, Operator: "=", Value: "x"}}},
+			"group":     {Where: &ConditionGroup{Conditions: []QueryCondition{{Field: field, Operator: "IS NULL"}}}},
+			"sort":      {Sorts: []SortCondition{{Field: field}}},
+		} {
+			if _, _, err := dryRunSQL(t, env.tool, qb); KindOf(err) != ErrKindInvalidArgument {
+				t.Errorf("%s 字段 %q: err = %v, want invalid argument", name, field, err)
+			}
+		}
+	}
+
+	for _, direction := range []string{"DESC; DROP TABLE test_items", "ASC--", "desc nulls first", "up"} {
+		qb := &QueryBuilder{Sorts: []SortCondition{{Field: "name", Direction: direction}}}
+		if _, _, err := dryRunSQL(t, env.tool, qb); KindOf(err) != ErrKindInvalidArgument {
+			t.Errorf("排序方向 %q: err = %v, want invalid argument", direction, err)
+		}
+	}
+}
+
+// 字段名解析为带表名并转义的列，值作为参数绑定
+func TestBuildQueryQuotesColumns(t *testing.T) {
+	env := newTestEnv(t)
+	sql, vars, err := dryRunSQL(t, env.tool, &QueryBuilder{
+		Conditions: []QueryCondition{
+			{Field: "Name", Operator: "=", Value: "x'; DROP TABLE test_items; --"},
+			{Field: "createdAt", Operator: "IS NOT NULL"},
+		},
+		Sorts: []SortCondition{{Field: "score", Direction: " desc "}, {Field: "ID"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"`test_items`.`name` = ?",
+		"`test_items`.`created_at` IS NOT NULL",
+		"ORDER BY `test_items`.`score` DESC,`test_items`.`id`",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("SQL 缺少 %q: %s", want, sql)
+		}
+	}
+	if strings.Contains(sql, "DROP") {
+		t.Errorf("值被拼接进 SQL: %s", sql)
+	}
+	if len(vars) == 0 || !reflect.DeepEqual(vars[0], "x'; DROP TABLE test_items; --") {
+		t.Errorf("vars = %v", vars)
+	}
+}
+
+// 别名和白名单
+func TestBuildQueryFieldPolicy(t *testing.T) {
+	env := newTestEnv(t)
+	env.tool.SetFieldPolicy(&testItem{}, FieldPolicy{
+		Allowed: []string{"name", "Score"},
+		Aliases: map[string]string{"title": "name", "points": "score", "secret": "created_at"},
+	})
+
+	tests := []struct {
+		field   string
+		wantCol string // 为空表示应被拒绝
+	}{
+		{"title", "`test_items`.`name`"},
+		{"points", "`test_items`.`score`"},
+		{"name", "`test_items`.`name`"},
+		{"Score", "`test_items`.`score`"},
+		{"secret", ""},
+		{"created_at", ""},
+		{"id", ""},
+	}
+	for _, tt := range tests {
+		qb := &QueryBuilder{
+			Conditions: []QueryCondition{{Field: tt.field, Operator: "!=", Value: "1"}},
+			Sorts:      []SortCondition{{Field: tt.field, Direction: "asc"}},
+		}
+		sql, _, err := dryRunSQL(t, env.tool, qb)
+		if tt.wantCol == "" {
+			if KindOf(err) != ErrKindInvalidArgument {
+				t.Errorf("字段 %q: err = %v, want invalid argument", tt.field, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("字段 %q: %v", tt.field, err)
+			continue
+		}
+		if !strings.Contains(sql, tt.wantCol+" != ?") || !strings.Contains(sql, "ORDER BY "+tt.wantCol) {
+			t.Errorf("字段 %q: SQL = %s", tt.field, sql)
+		}
+	}
+}
+
+// queryNames 执行查询，返回按 id 排序的名称
+func queryNames(t *testing.T, tool *CRUDTool, qb *QueryBuilder) ([]string, error) {
+	t.Helper()
+	var items []testItem
+	db, err := tool.BuildQuery(tool.DB, &items, qb)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Order("id").Find(&items).Error; err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, len(items))
+	for i, item := range items {
+		names[i] = item.Name
+	}
+	return names, nil
+}
+
+func TestBuildQueryOperators(t *testing.T) {
+	env := newTestEnv(t)
+	env.seed(t, "Apple", "apricot", "50%_off", "50 off", "a!b", "banana")
+	if err := env.tool.DB.Model(&testItem{}).Where("name = ?", "banana").Update("owner_id", 1).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	cond := func(field, op string, value interface{}) QueryCondition {
+		return QueryCondition{Field: field, Operator: op, Value: value}
+	}
+	tests := []struct {
+		name string
+		qb   *QueryBuilder
+		want []string
+	}{
+		{"等于", &QueryBuilder{Conditions: []QueryCondition{cond("name", "=", "Apple")}}, []string{"Apple"}},
+		{"IN", &QueryBuilder{Conditions: []QueryCondition{cond("score", "IN", []interface{}{1, "3"})}}, []string{"Apple", "50%_off"}},
+		{"NOT IN", &QueryBuilder{Conditions: []QueryCondition{cond("score", "not  in", []interface{}{1, 2, 3, 4})}}, []string{"a!b", "banana"}},
+		{"BETWEEN", &QueryBuilder{Conditions: []QueryCondition{cond("score", "BETWEEN", []interface{}{2, 4})}}, []string{"apricot", "50%_off", "50 off"}},
+		{"BETWEEN 忽略大小写", &QueryBuilder{Conditions: []QueryCondition{{Field: "name", Operator: "BETWEEN", Value: []interface{}{"AP", "APZ"}, IgnoreCase: true}}}, []string{"Apple", "apricot"}},
+		{"STARTS WITH 通配符作为字面量", &QueryBuilder{Conditions: []QueryCondition{cond("name", "STARTS WITH", "50%_")}}, []string{"50%_off"}},
+		{"ENDS WITH 转义字符", &QueryBuilder{Conditions: []QueryCondition{cond("name", "ENDS WITH", "!b")}}, []string{"a!b"}},
+		{"STARTS WITH 忽略大小写", &QueryBuilder{Conditions: []QueryCondition{{Field: "name", Operator: "STARTS WITH", Value: "AP", IgnoreCase: true}}}, []string{"Apple", "apricot"}},
+		{"ILIKE", &QueryBuilder{Conditions: []QueryCondition{cond("name", "ILIKE", "PRI")}}, []string{"apricot"}},
+		{"IS NULL", &QueryBuilder{Conditions: []QueryCondition{cond("owner_id", "IS NOT NULL", nil)}}, []string{"banana"}},
+		{"嵌套条件组", &QueryBuilder{Where: &ConditionGroup{
+			Logic:      "or",
+			Conditions: []QueryCondition{cond("name", "=", "banana")},
+			Groups: []ConditionGroup{{
+				Conditions: []QueryCondition{cond("score", ">=", 2), cond("name", "STARTS WITH", "50")},
+			}},
+		}}, []string{"50%_off", "50 off", "banana"}},
+		{"NOT", &QueryBuilder{Where: &ConditionGroup{
+			Not:        true,
+			Logic:      "or",
+			Conditions: []QueryCondition{cond("name", "=", "banana"), cond("score", "<=", 3)},
+		}}, []string{"50 off", "a!b"}},
+		{"条件和条件组为 AND", &QueryBuilder{
+			Conditions: []QueryCondition{cond("score", "<", 6)},
+			Where:      &ConditionGroup{Not: true, Conditions: []QueryCondition{cond("score", "<=", 4)}},
+		}, []string{"a!b"}},
+	}
+	for _, tt := range tests {
+		got, err := queryNames(t, env.tool, tt.qb)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBuildQueryInvalidConditions(t *testing.T) {
+	env := newTestEnv(t)
+	deep := &ConditionGroup{}
+	g := deep
+	for i := 0; i < maxGroupDepth; i++ {
+		g.Groups = []ConditionGroup{{}}
+		g = &g.Groups[0]
+	}
+	g.Conditions = []QueryCondition{{Field: "name", Operator: "IS NULL"}}
+
+	tests := map[string]*QueryBuilder{
+		"操作符":              {Conditions: []QueryCondition{{Field: "name", Operator: "= 1 OR 1 =", Value: "x"}}},
+		"BETWEEN 一个值":      {Conditions: []QueryCondition{{Field: "score", Operator: "BETWEEN", Value: []interface{}{1}}}},
+		"IN 空数组":           {Conditions: []QueryCondition{{Field: "score", Operator: "IN", Value: []interface{}{}}}},
+		"STARTS WITH 非字符串": {Conditions: []QueryCondition{{Field: "name", Operator: "STARTS WITH", Value: 1}}},
+		"数值类型":             {Conditions: []QueryCondition{{Field: "score", Operator: "=", Value: "abc"}}},
+		"组逻辑":              {Where: &ConditionGroup{Logic: "xor", Conditions: []QueryCondition{{Field: "name", Operator: "IS NULL"}}}},
+		"嵌套过深":             {Where: deep},
+	}
+	for name, qb := range tests {
+		if _, err := queryNames(t, env.tool, qb); KindOf(err) != ErrKindInvalidArgument {
+			t.Errorf("%s: err = %v, want invalid argument", name, err)
+		}
+	}
+}
+
+// LIKE 使用非反斜杠的转义字符，MySQL 默认 sql_mode 下也能解析
+func TestLikeEscape(t *testing.T) {
+	env := newTestEnv(t)
+	sql, vars, err := dryRunSQL(t, env.tool, &QueryBuilder{Conditions: []QueryCondition{{Field: "name", Operator: "STARTS WITH", Value: `a\%_!`}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(sql, "LIKE ? ESCAPE '!'") || strings.Contains(sql, `\`) {
+		t.Fatalf("SQL = %s", sql)
+	}
+	if vars[0] != `a\!%!_!!%` {
+		t.Fatalf("pattern = %v", vars[0])
+	}
+}
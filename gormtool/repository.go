@@ -0,0 +1,170 @@
+// gormtool\repository.go
+package gormtool
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+)
+
+// 批量操作类型
+const (
+	BatchCreate     = "create"
+	BatchUpdate     = "update"
+	BatchSoftDelete = "soft_delete"
+	BatchHardDelete = "hard_delete"
+)
+
+// PageResult 分页查询结果
+type PageResult[T any] struct {
+	Items []T        `json:"items"`
+	Page  Pagination `json:"page"`
+}
+
+// Repository 泛型仓储
+// 基于 CRUDTool 提供类型安全的 CRUD 操作，返回 *T / []T，
+// 传错模型类型会在编译期报错，而不是运行时反射失败
+//
+//	users := gormtool.NewRepository[models.User](cruder)
+//	user, err := users.GetByID(ctx, 1, "Tags")
+type Repository[T any] struct {
+	tool *CRUDTool
+}
+
+// NewRepository 创建泛型仓储
+func NewRepository[T any](tool *CRUDTool) *Repository[T] {
+	tool.cacheModelPrefix(new(T)) // 记录表名，缓存管理接口按表名查找模型
+	return &Repository[T]{tool: tool}
+}
+
+// Tool 返回底层的 CRUDTool
+func (r *Repository[T]) Tool() *CRUDTool {
+	return r.tool
+}
+
+// model 返回用于日志和缓存键的模型指针，与 gin 方法中传入的 &T{} 保持一致
+func (r *Repository[T]) model() *T {
+	return new(T)
+}
+
+// GetByID 根据ID查询单条记录（带缓存）
+func (r *Repository[T]) GetByID(ctx context.Context, id uint, preloads ...string) (*T, error) {
+	entity := r.model()
+	if _, err := r.tool.FindByID(ctx, entity, id, FindOptions{Preloads: preloads}); err != nil {
+		return nil, err
+	}
+	return entity, nil
+}
+
+// GetByIDUnscoped 根据ID查询单条记录（包含已软删除的记录）
+func (r *Repository[T]) GetByIDUnscoped(ctx context.Context, id uint, preloads ...string) (*T, error) {
+	entity := r.model()
+	if _, err := r.tool.FindByID(ctx, entity, id, FindOptions{Preloads: preloads, Unscoped: true}); err != nil {
+		return nil, err
+	}
+	return entity, nil
+}
+
+// List 使用查询构建器分页查询
+func (r *Repository[T]) List(ctx context.Context, qb *QueryBuilder, page, pageSize int) (*PageResult[T], error) {
+	items := make([]T, 0)
+	pagination, err := r.tool.FindPage(ctx, &items, qb, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &PageResult[T]{Items: items, Page: *pagination}, nil
+}
+
+// ListCursor 使用查询构建器游标分页查询
+func (r *Repository[T]) ListCursor(ctx context.Context, qb *QueryBuilder, req CursorRequest) (*PageResult[T], error) {
+	items := make([]T, 0)
+	pagination, err := r.tool.FindCursorPage(ctx, &items, qb, req)
+	if err != nil {
+		return nil, err
+	}
+	return &PageResult[T]{Items: items, Page: *pagination}, nil
+}
+
+// Create 创建记录，relations 中的关联字段会在同一事务中 Replace
+func (r *Repository[T]) Create(ctx context.Context, entity *T, relations ...string) error {
+	return r.tool.CreateRecord(ctx, entity, relations...)
+}
+
+// Update 先加载记录，通过 apply 修改后保存，relations 中的关联字段会被 Replace
+func (r *Repository[T]) Update(ctx context.Context, id uint, apply func(entity *T) error, relations ...string) (*T, error) {
+	entity := r.model()
+	var applyFn ApplyFunc
+	if apply != nil {
+		applyFn = func(model interface{}) error { return apply(model.(*T)) }
+	}
+	if err := r.tool.UpdateRecord(ctx, entity, id, applyFn, relations...); err != nil {
+		return nil, err
+	}
+	return entity, nil
+}
+
+// SoftDelete 软删除
+func (r *Repository[T]) SoftDelete(ctx context.Context, id uint) error {
+	return r.tool.SoftDelete(ctx, r.model(), id)
+}
+
+// HardDelete 硬删除
+func (r *Repository[T]) HardDelete(ctx context.Context, id uint) error {
+	return r.tool.HardDelete(ctx, r.model(), id)
+}
+
+// Restore 恢复软删除的记录
+func (r *Repository[T]) Restore(ctx context.Context, id uint) error {
+	return r.tool.Restore(ctx, r.model(), id)
+}
+
+// Batch 批量操作，operation 取值见 BatchCreate 等常量，返回受影响行数
+func (r *Repository[T]) Batch(ctx context.Context, entities []T, operation string) (int64, error) {
+	if len(entities) == 0 {
+		return 0, nil
+	}
+	return r.tool.Batch(ctx, &entities, operation)
+}
+
+// GetRelated 获取关联记录，R 为关联模型类型
+//
+//	tags, err := gormtool.GetRelated[models.User, models.Tag](ctx, users, 1, "Tags")
+func GetRelated[T, R any](ctx context.Context, r *Repository[T], id uint, associationName string) ([]R, error) {
+	related := make([]R, 0)
+	if err := r.tool.FindRelated(ctx, r.model(), id, associationName, &related); err != nil {
+		return nil, err
+	}
+	return related, nil
+}
+
+// AddRelated 添加关联关系
+func AddRelated[T, R any](ctx context.Context, r *Repository[T], id uint, associationName string, related ...*R) error {
+	return r.tool.AppendRelation(ctx, r.model(), id, associationName, related)
+}
+
+// replaceRelations 通过反射读取模型上的关联字段并 Replace
+func replaceRelations(tx *gorm.DB, model interface{}, relations []string) error {
+	for _, rel := range relations {
+		field := reflect.Indirect(reflect.ValueOf(model)).FieldByName(rel)
+		if !field.IsValid() {
+			return fmt.Errorf("invalid relation field: %s", rel)
+		}
+		if err := tx.Model(model).Association(rel).Replace(field.Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normalizePage 规范化分页参数
+func normalizePage(page, pageSize int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	return page, pageSize
+}
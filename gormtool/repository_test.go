@@ -0,0 +1,222 @@
+// gormtool\repository_test.go
+package gormtool
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// 创建、查询、更新、硬删除，更新后缓存中的记录随之更新
+func TestRepositoryCRUD(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	repo := NewRepository[testItem](env.tool)
+
+	item := &testItem{Name: "a", Score: 1}
+	if err := repo.Create(ctx, item); err != nil || item.ID == 0 {
+		t.Fatalf("Create = %+v, %v", item, err)
+	}
+	got, err := repo.GetByID(ctx, item.ID)
+	if err != nil || got.Name != "a" {
+		t.Fatalf("GetByID = %+v, %v", got, err)
+	}
+
+	updated, err := repo.Update(ctx, item.ID, func(e *testItem) error {
+		e.Name = "b"
+		return nil
+	})
+	if err != nil || updated.Name != "b" || updated.ID != item.ID {
+		t.Fatalf("Update = %+v, %v", updated, err)
+	}
+	if got, err = repo.GetByID(ctx, item.ID); err != nil || got.Name != "b" {
+		t.Fatalf("更新后 GetByID = %+v, %v", got, err)
+	}
+
+	errApply := errors.New("拒绝修改")
+	if _, err := repo.Update(ctx, item.ID, func(e *testItem) error {
+		e.Name = "c"
+		return errApply
+	}); !errors.Is(err, errApply) {
+		t.Fatalf("apply 失败时 Update err = %v", err)
+	}
+	if got, _ = repo.GetByID(ctx, item.ID); got.Name != "b" {
+		t.Fatalf("apply 失败后记录被修改: %+v", got)
+	}
+	if _, err := repo.Update(ctx, 999, nil); !IsNotFound(err) {
+		t.Fatalf("更新不存在的记录 err = %v", err)
+	}
+
+	if err := repo.HardDelete(ctx, item.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetByIDUnscoped(ctx, item.ID); !IsNotFound(err) {
+		t.Fatalf("硬删除后 GetByIDUnscoped err = %v", err)
+	}
+	if err := repo.HardDelete(ctx, item.ID); !IsNotFound(err) {
+		t.Fatalf("重复删除 err = %v", err)
+	}
+}
+
+// 软删除后只能通过 Unscoped 查到，恢复后清除“不存在”缓存
+func TestRepositorySoftDeleteRestore(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	repo := NewRepository[testItem](env.tool)
+	id := env.seed(t, "a")[0].ID
+
+	if err := repo.SoftDelete(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetByID(ctx, id); !IsNotFound(err) {
+		t.Fatalf("软删除后 GetByID err = %v", err)
+	}
+	deleted, err := repo.GetByIDUnscoped(ctx, id)
+	if err != nil || !deleted.DeletedAt.Valid {
+		t.Fatalf("GetByIDUnscoped = %+v, %v", deleted, err)
+	}
+	if err := repo.SoftDelete(ctx, id); !IsNotFound(err) {
+		t.Fatalf("重复软删除 err = %v", err)
+	}
+
+	if err := repo.Restore(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := repo.GetByID(ctx, id); err != nil || got.DeletedAt.Valid {
+		t.Fatalf("恢复后 GetByID = %+v, %v", got, err)
+	}
+	if err := repo.Restore(ctx, 999); !IsNotFound(err) {
+		t.Fatalf("恢复不存在的记录 err = %v", err)
+	}
+}
+
+// 分页和游标分页返回 []T，软删除的记录不出现在列表中
+func TestRepositoryList(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	repo := NewRepository[testItem](env.tool)
+	items := env.seed(t, "a", "b", "c", "d", "e")
+	if err := repo.SoftDelete(ctx, items[4].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	qb := &QueryBuilder{Sorts: []SortCondition{{Field: "score", Direction: "desc"}}}
+	page, err := repo.List(ctx, qb, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Page.Total != 4 || page.Page.Page != 2 || !equalIDs(cursorIDs(page.Items), []uint{items[0].ID}) {
+		t.Fatalf("List 第 2 页 = %v %+v", cursorIDs(page.Items), page.Page)
+	}
+
+	var ids []uint
+	req := CursorRequest{PageSize: 3}
+	for {
+		page, err := repo.ListCursor(ctx, qb, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, cursorIDs(page.Items)...)
+		if page.Page.NextCursor == "" {
+			break
+		}
+		req.Cursor = page.Page.NextCursor
+	}
+	want := []uint{items[3].ID, items[2].ID, items[1].ID, items[0].ID}
+	if !equalIDs(ids, want) {
+		t.Fatalf("ListCursor = %v, want %v", ids, want)
+	}
+
+	if _, err := repo.ListCursor(ctx, qb, CursorRequest{Cursor: "bogus", PageSize: 3}); err == nil {
+		t.Fatal("无效游标应返回错误")
+	}
+}
+
+// 批量创建、更新、软删除和硬删除，返回受影响行数
+func TestRepositoryBatch(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	repo := NewRepository[testItem](env.tool)
+
+	entities := []testItem{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	if n, err := repo.Batch(ctx, entities, BatchCreate); err != nil || n != 3 {
+		t.Fatalf("批量创建 = %d, %v", n, err)
+	}
+	var created []testItem
+	if err := env.tool.DB.Order("id").Find(&created).Error; err != nil || len(created) != 3 {
+		t.Fatalf("批量创建后记录 = %v, %v", created, err)
+	}
+
+	// 先缓存，批量更新后缓存被清除
+	if _, err := repo.GetByID(ctx, created[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	for i := range created {
+		created[i].Score = 10
+	}
+	if n, err := repo.Batch(ctx, created, BatchUpdate); err != nil || n != 3 {
+		t.Fatalf("批量更新 = %d, %v", n, err)
+	}
+	if got, err := repo.GetByID(ctx, created[0].ID); err != nil || got.Score != 10 {
+		t.Fatalf("批量更新后 GetByID = %+v, %v", got, err)
+	}
+
+	if n, err := repo.Batch(ctx, created[:2], BatchSoftDelete); err != nil || n != 2 {
+		t.Fatalf("批量软删除 = %d, %v", n, err)
+	}
+	if _, err := repo.GetByID(ctx, created[0].ID); !IsNotFound(err) {
+		t.Fatalf("批量软删除后 GetByID err = %v", err)
+	}
+	if n, err := repo.Batch(ctx, created, BatchHardDelete); err != nil || n != 3 {
+		t.Fatalf("批量硬删除 = %d, %v", n, err)
+	}
+	var count int64
+	if env.tool.DB.Unscoped().Model(&testItem{}).Count(&count); count != 0 {
+		t.Fatalf("批量硬删除后剩余 %d 条", count)
+	}
+
+	if n, err := repo.Batch(ctx, nil, BatchCreate); err != nil || n != 0 {
+		t.Fatalf("空切片 = %d, %v", n, err)
+	}
+	var svcErr *Error
+	if _, err := repo.Batch(ctx, entities, "truncate"); !errors.As(err, &svcErr) || svcErr.Kind != ErrKindInvalidArgument {
+		t.Fatalf("不支持的操作 err = %v", err)
+	}
+}
+
+// AddRelated 添加关联记录后 GetRelated 返回它们，主记录不存在时返回 not found
+func TestRepositoryRelated(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	owners := NewRepository[testOwner](env.tool)
+	owner := &testOwner{Name: "o"}
+	if err := owners.Create(ctx, owner); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := AddRelated(ctx, owners, owner.ID, "Items", &testItem{Name: "a"}, &testItem{Name: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	related, err := GetRelated[testOwner, testItem](ctx, owners, owner.ID, "Items")
+	if err != nil || len(related) != 2 {
+		t.Fatalf("GetRelated = %v, %v", related, err)
+	}
+	for _, item := range related {
+		if item.OwnerID == nil || *item.OwnerID != owner.ID {
+			t.Fatalf("关联记录 OwnerID = %v, want %d", item.OwnerID, owner.ID)
+		}
+	}
+
+	if got, err := owners.GetByID(ctx, owner.ID, "Items"); err != nil || len(got.Items) != 2 {
+		t.Fatalf("预加载 Items = %+v, %v", got, err)
+	}
+	if _, err := GetRelated[testOwner, testItem](ctx, owners, 999, "Items"); !IsNotFound(err) {
+		t.Fatalf("主记录不存在 GetRelated err = %v", err)
+	}
+	if err := AddRelated(ctx, owners, 999, "Items", &testItem{Name: "c"}); !IsNotFound(err) {
+		t.Fatalf("主记录不存在 AddRelated err = %v", err)
+	}
+	if _, err := GetRelated[testOwner, testItem](ctx, owners, owner.ID, "Nope"); err == nil {
+		t.Fatal("未知的关联应返回错误")
+	}
+}
@@ -0,0 +1,148 @@
+// gormtool\request_context.go
+package gormtool
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 请求上下文日志字段
+// RequestIDMiddleware 把请求 ID、路由和客户端 IP 保存到请求的 context 中，认证中间件通过 SetUserID 补充登录用户 ID，
+// 之后 LogOperation、DefaultLogger、SlogLogger（包括 Slog() 返回的 *slog.Logger）记录日志时自动带上这些字段，
+// 同一个请求的日志可以按 request_id 关联。自定义 Logger 可以通过 ContextLogFields 取得这些字段。
+
+// RequestIDHeader 请求 ID 的请求头和响应头
+const RequestIDHeader = "X-Request-ID"
+
+// UserIDKey 认证中间件保存登录用户 ID 使用的 gin.Context 键，推荐通过 SetUserID 设置
+const UserIDKey = "user_id"
+
+// maxRequestIDLength 客户端传入的请求 ID 的最大长度，超过或包含不可见字符时重新生成
+const maxRequestIDLength = 128
+
+type logFieldsKey struct{}
+
+// WithLogFields 返回带有附加日志字段的 context，同名字段覆盖外层的值
+func WithLogFields(ctx context.Context, fields map[string]interface{}) context.Context {
+	parent, _ := ctx.Value(logFieldsKey{}).(map[string]interface{})
+	merged := make(map[string]interface{}, len(parent)+len(fields))
+	for k, v := range parent {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, logFieldsKey{}, merged)
+}
+
+// ContextLogFields 返回 ctx 中的日志字段（WithLogFields 附加的字段以及 trace_id、span_id），没有时返回 nil
+func ContextLogFields(ctx context.Context) map[string]interface{} {
+	if ctx == nil {
+		return nil
+	}
+	stored, _ := ctx.Value(logFieldsKey{}).(map[string]interface{})
+	trace := traceFields(ctx)
+	if len(stored) == 0 {
+		return trace
+	}
+	fields := make(map[string]interface{}, len(stored)+len(trace))
+	for k, v := range stored {
+		fields[k] = v
+	}
+	for k, v := range trace {
+		fields[k] = v
+	}
+	return fields
+}
+
+// mergeContextFields 把 ctx 中的日志字段合并到 fields，fields 中已有的字段优先
+func mergeContextFields(ctx context.Context, fields map[string]interface{}) map[string]interface{} {
+	ctxFields := ContextLogFields(ctx)
+	if len(ctxFields) == 0 {
+		return fields
+	}
+	merged := make(map[string]interface{}, len(ctxFields)+len(fields))
+	for k, v := range ctxFields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return merged
+}
+
+// RequestIDFromContext 返回 ctx 中的请求 ID，没有时返回空字符串
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	fields, _ := ctx.Value(logFieldsKey{}).(map[string]interface{})
+	id, _ := fields["request_id"].(string)
+	return id
+}
+
+// RequestIDMiddleware 读取请求头中的 X-Request-ID，没有（或不合法）时生成一个，
+// 在响应头中返回，并把 request_id、method、route 和 client_ip 保存到请求的 context 中
+// 应放在其他中间件之前，这样后续中间件的日志也能带上请求 ID；
+// 此时认证中间件还没有运行，user_id 只能由认证中间件调用 SetUserID 写入 context
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Header(RequestIDHeader, id)
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		fields := map[string]interface{}{
+			"request_id": id,
+			"method":     c.Request.Method,
+			"route":      route,
+			"client_ip":  c.ClientIP(),
+		}
+		c.Request = c.Request.WithContext(WithLogFields(c.Request.Context(), fields))
+		c.Next()
+	}
+}
+
+// SetUserID 由认证中间件在确认用户身份后调用，之后该请求的日志都带上 user_id
+// 只调用 c.Set(UserIDKey, ...) 不会写入 context，这种情况下只有访问日志带 user_id
+func SetUserID(c *gin.Context, userID interface{}) {
+	c.Set(UserIDKey, userID)
+	c.Request = c.Request.WithContext(WithLogFields(c.Request.Context(), map[string]interface{}{
+		"user_id": userID,
+	}))
+}
+
+// requestID 返回当前请求的 ID，用于填充 Response.RequestID
+func requestID(c *gin.Context) string {
+	if id := RequestIDFromContext(c.Request.Context()); id != "" {
+		return id
+	}
+	return c.Writer.Header().Get(RequestIDHeader)
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID 生成 32 位十六进制的随机 ID
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,121 @@
+// gormtool\request_context_test.go
+package gormtool
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestIDRouter 返回使用 RequestIDMiddleware 的路由，/fail 返回 404 的 Response，/id 返回 context 中的请求 ID
+func requestIDRouter(mw ...gin.HandlerFunc) *gin.Engine {
+	r := newTestRouter()
+	r.Use(RequestIDMiddleware())
+	r.Use(mw...)
+	r.GET("/fail", func(c *gin.Context) {
+		RespondError(c, NewError(ErrKindNotFound, "get_by_id", "记录不存在", nil))
+	})
+	r.GET("/id", func(c *gin.Context) {
+		c.String(http.StatusOK, RequestIDFromContext(c.Request.Context()))
+	})
+	return r
+}
+
+func TestRequestIDMiddlewareGenerates(t *testing.T) {
+	r := requestIDRouter()
+
+	w, resp := doRequest(t, r, http.MethodGet, "/fail", "")
+	id := w.Header().Get(RequestIDHeader)
+	if len(id) != 32 {
+		t.Fatalf("生成的请求 ID = %q, want 32 位十六进制", id)
+	}
+	if resp.RequestID != id {
+		t.Fatalf("Response.RequestID = %q, want %q", resp.RequestID, id)
+	}
+
+	w2 := httptest.NewRecorder()
+	r.ServeHTTP(w2, httptest.NewRequest(http.MethodGet, "/id", nil))
+	if got := w2.Body.String(); got != w2.Header().Get(RequestIDHeader) || got == id {
+		t.Fatalf("context 中的请求 ID = %q, 响应头 = %q, 上一个请求 = %q", got, w2.Header().Get(RequestIDHeader), id)
+	}
+}
+
+func TestRequestIDMiddlewareIncomingHeader(t *testing.T) {
+	r := requestIDRouter()
+	cases := []struct {
+		name, header string
+		keep         bool
+	}{
+		{"合法", "req-123", true},
+		{"包含空格", "req 123", false},
+		{"包含换行", "req\n123", false},
+		{"过长", strings.Repeat("a", maxRequestIDLength+1), false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/id", nil)
+			req.Header.Set(RequestIDHeader, tc.header)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			got := w.Header().Get(RequestIDHeader)
+			if tc.keep && got != tc.header {
+				t.Fatalf("响应头 = %q, want %q", got, tc.header)
+			}
+			if !tc.keep && (got == tc.header || len(got) != 32) {
+				t.Fatalf("不合法的请求 ID 未重新生成: %q", got)
+			}
+			if w.Body.String() != got {
+				t.Fatalf("context 中的请求 ID = %q, want %q", w.Body.String(), got)
+			}
+		})
+	}
+}
+
+// 认证中间件调用 SetUserID 后，LogOperation 的日志带上 request_id 和 user_id
+func TestSetUserIDLogFields(t *testing.T) {
+	var buf bytes.Buffer
+	tool := NewCRUDTool(nil, nil, NewSlogLogger(slog.New(slog.NewJSONHandler(&buf, nil))))
+
+	auth := func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "" {
+			SetUserID(c, 7)
+		}
+	}
+	r := requestIDRouter(auth)
+	r.GET("/log", func(c *gin.Context) {
+		tool.LogOperation(c.Request.Context(), "get_by_id", nil, 1, nil, nil)
+		c.Status(http.StatusNoContent)
+	})
+
+	for _, withUser := range []bool{false, true} {
+		buf.Reset()
+		req := httptest.NewRequest(http.MethodGet, "/log", nil)
+		req.Header.Set(RequestIDHeader, "req-log")
+		if withUser {
+			req.Header.Set("Authorization", "token")
+		}
+		r.ServeHTTP(httptest.NewRecorder(), req)
+
+		var entry map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+			t.Fatalf("解析日志失败: %v\n%s", err, buf.String())
+		}
+		if entry["request_id"] != "req-log" || entry["route"] != "/log" || entry["method"] != http.MethodGet {
+			t.Fatalf("日志缺少请求字段: %v", entry)
+		}
+		userID, ok := entry["user_id"]
+		if withUser && userID != float64(7) {
+			t.Fatalf("user_id = %v, want 7", userID)
+		}
+		if !withUser && ok {
+			t.Fatalf("未认证的请求不应带 user_id: %v", entry)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+// gormtool\resource.go
+package gormtool
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 资源路由操作
+const (
+	OpList       = "list"
+	OpGet        = "get"
+	OpCreate     = "create"
+	OpUpdate     = "update"
+	OpPatch      = "patch"
+	OpSoftDelete = "soft_delete"
+	OpRestore    = "restore"
+	OpHardDelete = "hard_delete"
+	OpBatch      = "batch"
+	OpRelations  = "relations"
+	OpAggregate  = "aggregate"
+)
+
+// ResourceOptions 资源注册选项
+type ResourceOptions struct {
+	// Operations 允许的操作，为空表示全部允许
+	Operations []string
+	// Preloads 查询单条和列表时预加载的关联
+	Preloads []string
+	// Relations 创建/更新时同步 Replace 的关联字段，同时生成 /:id/<relation> 关联接口
+	Relations []string
+}
+
+func (o ResourceOptions) allowed(op string) bool {
+	if len(o.Operations) == 0 {
+		return true
+	}
+	for _, allowed := range o.Operations {
+		if allowed == op {
+			return true
+		}
+	}
+	return false
+}
+
+// resource 一个已注册的资源，每个请求都会创建新的模型实例
+type resource struct {
+	tool      *CRUDTool
+	modelType reflect.Type
+	opts      ResourceOptions
+}
+
+func (r *resource) newModel() interface{} {
+	return reflect.New(r.modelType).Interface()
+}
+
+func (r *resource) newSlice() interface{} {
+	return reflect.New(reflect.SliceOf(r.modelType)).Interface()
+}
+
+// RegisterResource 为模型注册一整套 REST 路由
+//
+//	cruder.RegisterResource(r, "/tags", &models.Tag{}, gormtool.ResourceOptions{})
+//
+// 生成的路由：
+//
+//	GET    /tags                       列表（分页）
+//	GET    /tags/:id                   查询单条
+//	POST   /tags                       创建
+//	PUT    /tags/:id                   更新
+//	PATCH  /tags/:id                   部分更新
+//	DELETE /tags/:id                   软删除
+//	PUT    /tags/:id/restore           恢复软删除
+//	DELETE /tags/:id/hard              硬删除
+//	POST   /tags/batch/:operation      批量操作（create/update/soft_delete/hard_delete）
+//	GET    /tags/:id/<relation>        获取关联记录
+//	POST   /tags/:id/<relation>        添加关联
+//	GET    /tags/aggregate             分组聚合（见 ParseAggregateParams）
+func (t *CRUDTool) RegisterResource(router gin.IRouter, path string, model interface{}, opts ResourceOptions) gin.IRouter {
+	modelType := reflect.TypeOf(model)
+	for modelType.Kind() == reflect.Ptr {
+		modelType = modelType.Elem()
+	}
+	if modelType.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("gormtool: RegisterResource 需要结构体模型，得到 %T", model))
+	}
+
+	res := &resource{tool: t, modelType: modelType, opts: opts}
+	t.cacheModelPrefix(model) // 记录表名，缓存管理接口按表名查找模型
+	group := router.Group(path)
+
+	if opts.allowed(OpList) {
+		group.GET("", res.list)
+	}
+	if opts.allowed(OpGet) {
+		group.GET("/:id", res.get)
+	}
+	if opts.allowed(OpCreate) {
+		group.POST("", res.create)
+	}
+	if opts.allowed(OpUpdate) {
+		group.PUT("/:id", res.update)
+	}
+	if opts.allowed(OpPatch) {
+		group.PATCH("/:id", res.patch)
+	}
+	if opts.allowed(OpSoftDelete) {
+		group.DELETE("/:id", res.softDelete)
+	}
+	if opts.allowed(OpRestore) {
+		group.PUT("/:id/restore", res.restore)
+	}
+	if opts.allowed(OpHardDelete) {
+		group.DELETE("/:id/hard", res.hardDelete)
+	}
+	if opts.allowed(OpBatch) {
+		group.POST("/batch/:operation", res.batch)
+	}
+	if opts.allowed(OpAggregate) {
+		group.GET("/aggregate", res.aggregate)
+	}
+	if opts.allowed(OpRelations) {
+		for _, rel := range opts.Relations {
+			relatedType, err := relationElemType(modelType, rel)
+			if err != nil {
+				panic(fmt.Sprintf("gormtool: RegisterResource %s: %v", path, err))
+			}
+			relPath := "/:id/" + strings.ToLower(rel)
+			group.GET(relPath, res.getRelated(rel, relatedType))
+			group.POST(relPath, res.addRelated(rel, relatedType))
+		}
+	}
+	return group
+}
+
+func (r *resource) list(c *gin.Context) {
+	qb := &QueryBuilder{Preloads: r.opts.Preloads}
+	r.tool.GetByQueryBuilder(c, r.newSlice(), qb)
+}
+
+func (r *resource) get(c *gin.Context) {
+	r.tool.GetByID(c, r.newModel(), r.opts.Preloads...)
+}
+
+func (r *resource) create(c *gin.Context) {
+	r.tool.CreateWithRelations(c, r.newModel(), r.opts.Relations)
+}
+
+func (r *resource) update(c *gin.Context) {
+	r.tool.UpdateWithRelations(c, r.newModel(), r.opts.Relations)
+}
+
+func (r *resource) patch(c *gin.Context) {
+	r.tool.PatchByID(c, r.newModel())
+}
+
+func (r *resource) softDelete(c *gin.Context) {
+	r.tool.SoftDeleteByID(c, r.newModel())
+}
+
+func (r *resource) restore(c *gin.Context) {
+	r.tool.RestoreSoftDelete(c, r.newModel())
+}
+
+func (r *resource) hardDelete(c *gin.Context) {
+	r.tool.HardDeleteByID(c, r.newModel())
+}
+
+func (r *resource) batch(c *gin.Context) {
+	r.tool.BatchOperation(c, r.newSlice(), c.Param("operation"))
+}
+
+func (r *resource) aggregate(c *gin.Context) {
+	r.tool.GetAggregate(c, r.newModel(), nil, nil)
+}
+
+func (r *resource) getRelated(rel string, relatedType reflect.Type) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result := reflect.New(reflect.SliceOf(relatedType)).Interface()
+		r.tool.GetRelated(c, r.newModel(), rel, result)
+	}
+}
+
+func (r *resource) addRelated(rel string, relatedType reflect.Type) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		related := reflect.New(relatedType).Interface()
+		r.tool.AddRelation(c, r.newModel(), rel, related)
+	}
+}
+
+// relationElemType 返回关联字段的元素类型，支持 T、*T、[]T、[]*T
+func relationElemType(modelType reflect.Type, rel string) (reflect.Type, error) {
+	field, ok := modelType.FieldByName(rel)
+	if !ok {
+		return nil, fmt.Errorf("invalid relation field: %s", rel)
+	}
+	typ := field.Type
+	for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("relation field %s is not a struct", rel)
+	}
+	return typ, nil
+}
@@ -0,0 +1,195 @@
+// gormtool\resource_test.go
+package gormtool
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// resourceRouter 注册 /items（全部操作）和 /owners（带 Items 关联）两个资源
+func resourceRouter(env *testEnv) *gin.Engine {
+	r := newTestRouter()
+	env.tool.RegisterResource(r, "/items", &testItem{}, ResourceOptions{})
+	env.tool.RegisterResource(r, "/owners", &testOwner{}, ResourceOptions{
+		Operations: []string{OpGet, OpCreate, OpRelations},
+		Preloads:   []string{"Items"},
+		Relations:  []string{"Items"},
+	})
+	return r
+}
+
+// dataMap 返回 Response.Data 中的对象
+func dataMap(t *testing.T, resp Response) map[string]interface{} {
+	t.Helper()
+	data, ok := resp.Data.(map[string]interface{})
+	if !ok {
+		t.Fatalf("Data = %#v, want object", resp.Data)
+	}
+	return data
+}
+
+// 单条记录的增删改查、软删除与恢复、硬删除
+func TestResourceRecordRoutes(t *testing.T) {
+	env := newTestEnv(t)
+	r := resourceRouter(env)
+
+	w, resp := doRequest(t, r, http.MethodPost, "/items", `{"Name":"a","Score":1}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST /items = %d %+v", w.Code, resp)
+	}
+	id := uint(dataMap(t, resp)["ID"].(float64))
+	path := fmt.Sprintf("/items/%d", id)
+
+	if w, resp = doRequest(t, r, http.MethodGet, path, ""); w.Code != http.StatusOK || dataMap(t, resp)["Name"] != "a" {
+		t.Fatalf("GET %s = %d %+v", path, w.Code, resp)
+	}
+	if w, resp = doRequest(t, r, http.MethodPut, path, `{"Name":"b","Score":2}`); w.Code != http.StatusOK || dataMap(t, resp)["Name"] != "b" {
+		t.Fatalf("PUT %s = %d %+v", path, w.Code, resp)
+	}
+	w, resp = doRequest(t, r, http.MethodPatch, path, `{"Score":7}`)
+	if data := dataMap(t, resp); w.Code != http.StatusOK || data["Score"] != float64(7) || data["Name"] != "b" {
+		t.Fatalf("PATCH %s = %d %+v", path, w.Code, resp)
+	}
+
+	if w, resp = doRequest(t, r, http.MethodDelete, path, ""); w.Code != http.StatusOK {
+		t.Fatalf("DELETE %s = %d %+v", path, w.Code, resp)
+	}
+	if w, _ = doRequest(t, r, http.MethodGet, path, ""); w.Code != http.StatusNotFound {
+		t.Fatalf("软删除后 GET = %d, want 404", w.Code)
+	}
+	if w, resp = doRequest(t, r, http.MethodPut, path+"/restore", ""); w.Code != http.StatusOK {
+		t.Fatalf("PUT %s/restore = %d %+v", path, w.Code, resp)
+	}
+	if w, resp = doRequest(t, r, http.MethodGet, path, ""); w.Code != http.StatusOK || dataMap(t, resp)["Score"] != float64(7) {
+		t.Fatalf("恢复后 GET = %d %+v", w.Code, resp)
+	}
+
+	if w, resp = doRequest(t, r, http.MethodDelete, path+"/hard", ""); w.Code != http.StatusOK {
+		t.Fatalf("DELETE %s/hard = %d %+v", path, w.Code, resp)
+	}
+	if w, _ = doRequest(t, r, http.MethodPut, path+"/restore", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("硬删除后恢复 = %d, want 404", w.Code)
+	}
+}
+
+// 列表、批量操作和聚合
+func TestResourceCollectionRoutes(t *testing.T) {
+	env := newTestEnv(t)
+	r := resourceRouter(env)
+
+	w, resp := doRequest(t, r, http.MethodPost, "/items/batch/create", `[{"Name":"a","Score":1},{"Name":"b","Score":2},{"Name":"b","Score":3}]`)
+	if w.Code != http.StatusOK || resp.Data != float64(3) {
+		t.Fatalf("批量创建 = %d %+v", w.Code, resp)
+	}
+
+	w, resp = doRequest(t, r, http.MethodGet, "/items?sort=-score&pagesize=2", "")
+	items, _ := resp.Data.([]interface{})
+	if w.Code != http.StatusOK || len(items) != 2 || resp.Page == nil || resp.Page.Total != 3 {
+		t.Fatalf("GET /items = %d %+v", w.Code, resp)
+	}
+	if first := items[0].(map[string]interface{}); first["Score"] != float64(3) {
+		t.Fatalf("排序后第一条 = %v", first)
+	}
+
+	w, resp = doRequest(t, r, http.MethodGet, "/items/aggregate?group_by=name&aggregate=count&sort=name", "")
+	rows, _ := resp.Data.([]interface{})
+	if w.Code != http.StatusOK || len(rows) != 2 {
+		t.Fatalf("GET /items/aggregate = %d %+v", w.Code, resp)
+	}
+	if row := rows[1].(map[string]interface{}); row["name"] != "b" || row["count"] != float64(2) {
+		t.Fatalf("聚合结果 = %v", rows)
+	}
+
+	if w, resp = doRequest(t, r, http.MethodPost, "/items/batch/soft_delete", `[{"ID":1},{"ID":2}]`); w.Code != http.StatusOK || resp.Data != float64(2) {
+		t.Fatalf("批量软删除 = %d %+v", w.Code, resp)
+	}
+	if _, resp = doRequest(t, r, http.MethodGet, "/items", ""); resp.Page == nil || resp.Page.Total != 1 {
+		t.Fatalf("批量软删除后列表 = %+v", resp)
+	}
+}
+
+// 关联接口，以及 Operations 之外的路由不注册
+func TestResourceRelationRoutes(t *testing.T) {
+	env := newTestEnv(t)
+	r := resourceRouter(env)
+
+	w, resp := doRequest(t, r, http.MethodPost, "/owners", `{"Name":"o","Items":[{"Name":"a"}]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST /owners = %d %+v", w.Code, resp)
+	}
+	id := uint(dataMap(t, resp)["ID"].(float64))
+	path := fmt.Sprintf("/owners/%d", id)
+
+	if w, resp = doRequest(t, r, http.MethodPost, path+"/items", `{"Name":"b"}`); w.Code != http.StatusOK {
+		t.Fatalf("POST %s/items = %d %+v", path, w.Code, resp)
+	}
+	w, resp = doRequest(t, r, http.MethodGet, path+"/items", "")
+	if related, _ := resp.Data.([]interface{}); w.Code != http.StatusOK || len(related) != 2 {
+		t.Fatalf("GET %s/items = %d %+v", path, w.Code, resp)
+	}
+	w, resp = doRequest(t, r, http.MethodGet, path, "")
+	if items, _ := dataMap(t, resp)["Items"].([]interface{}); w.Code != http.StatusOK || len(items) != 2 {
+		t.Fatalf("GET %s 预加载 Items = %d %+v", path, w.Code, resp)
+	}
+
+	for _, route := range [][2]string{
+		{http.MethodGet, "/owners"},
+		{http.MethodPut, path},
+		{http.MethodDelete, path},
+		{http.MethodPost, "/owners/batch/create"},
+	} {
+		if w, _ := doRequest(t, r, route[0], route[1], `{}`); w.Code != http.StatusNotFound {
+			t.Errorf("未允许的操作 %s %s = %d, want 404", route[0], route[1], w.Code)
+		}
+	}
+}
+
+// 不存在的记录返回 404，非法 ID、请求体和参数返回 400
+func TestResourceErrors(t *testing.T) {
+	env := newTestEnv(t)
+	r := resourceRouter(env)
+	env.seed(t, "a")
+
+	tests := []struct {
+		method, target, body string
+		wantCode             int
+		wantKind             ErrorKind
+	}{
+		{http.MethodGet, "/items/999", "", http.StatusNotFound, ErrKindNotFound},
+		{http.MethodPut, "/items/999", `{"Name":"x"}`, http.StatusNotFound, ErrKindNotFound},
+		{http.MethodPatch, "/items/999", `{"Name":"x"}`, http.StatusNotFound, ErrKindNotFound},
+		{http.MethodDelete, "/items/999", "", http.StatusNotFound, ErrKindNotFound},
+		{http.MethodPut, "/items/999/restore", "", http.StatusNotFound, ErrKindNotFound},
+		{http.MethodDelete, "/items/999/hard", "", http.StatusNotFound, ErrKindNotFound},
+		{http.MethodGet, "/owners/999/items", "", http.StatusNotFound, ErrKindNotFound},
+		{http.MethodPost, "/owners/999/items", `{"Name":"x"}`, http.StatusNotFound, ErrKindNotFound},
+
+		{http.MethodGet, "/items/abc", "", http.StatusBadRequest, ErrKindInvalidID},
+		{http.MethodPut, "/items/abc/restore", "", http.StatusBadRequest, ErrKindInvalidID},
+		{http.MethodDelete, "/items/-1", "", http.StatusBadRequest, ErrKindInvalidID},
+		{http.MethodPost, "/items", `{"Name":`, http.StatusBadRequest, ErrKindBind},
+		{http.MethodPut, "/items/1", `{"Score":"high"}`, http.StatusBadRequest, ErrKindBind},
+		{http.MethodPatch, "/items/1", `{"Nope":1}`, http.StatusBadRequest, ErrKindInvalidArgument},
+		{http.MethodPatch, "/items/1", `{"ID":2}`, http.StatusBadRequest, ErrKindInvalidArgument},
+		{http.MethodPatch, "/items/1", `{}`, http.StatusBadRequest, ErrKindInvalidArgument},
+		{http.MethodPost, "/items/batch/truncate", `[]`, http.StatusBadRequest, ErrKindInvalidArgument},
+		{http.MethodPost, "/items/batch/create", `{"Name":"x"}`, http.StatusBadRequest, ErrKindBind},
+		{http.MethodGet, "/items?filter[nope]=1", "", http.StatusBadRequest, ErrKindInvalidArgument},
+		{http.MethodGet, "/items?sort=-nope", "", http.StatusBadRequest, ErrKindInvalidArgument},
+		{http.MethodGet, "/items/aggregate?aggregate=median:score", "", http.StatusBadRequest, ErrKindInvalidArgument},
+	}
+	for _, tt := range tests {
+		w, resp := doRequest(t, r, tt.method, tt.target, tt.body)
+		if w.Code != tt.wantCode || resp.ErrorCode != tt.wantKind {
+			t.Errorf("%s %s = %d %s %q; want %d %s", tt.method, tt.target, w.Code, resp.ErrorCode, resp.Message, tt.wantCode, tt.wantKind)
+		}
+	}
+
+	var item testItem
+	if err := env.tool.DB.First(&item, 1).Error; err != nil || item.Name != "a" || item.Score != 1 {
+		t.Fatalf("失败的请求修改了记录: %+v, %v", item, err)
+	}
+}
@@ -0,0 +1,16 @@
+// gormtool\schema.go
+package gormtool
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ParseSchema 解析模型对应的 GORM schema（GORM 内部有缓存）
+func (t *CRUDTool) ParseSchema(model interface{}) (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: t.DB}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
+	}
+	return stmt.Schema, nil
+}
@@ -0,0 +1,496 @@
+// gormtool\search.go
+package gormtool
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"html"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// 全文搜索（SQLite FTS5）
+// 为注册的模型维护 <表名>_fts 虚拟表，rowid 为模型主键，
+// 通过 GORM 回调在创建、更新、删除后同步；列表接口的 search 参数
+// 按相关度排序，并在每条结果的 _highlight 中返回高亮片段。
+// 软删除的记录保留在索引中，查询时由主表的默认条件过滤。
+//
+// go-sqlite3 需要使用 sqlite_fts5 构建标签编译：go build -tags sqlite_fts5
+// 未启用 FTS5 时退化为对搜索字段的 LIKE 匹配：每个词需出现在任一字段中，
+// 不维护索引表，没有相关度排序，高亮片段在查询结果上生成。
+
+// HighlightKey 搜索结果中高亮片段的 JSON 键名
+const HighlightKey = "_highlight"
+
+// 高亮片段是 HTML：字段内容经过转义，只有匹配的词被 <mark> 包裹。
+// FTS5 的 highlight() 先用私有区字符标记匹配位置，转义后再替换为标签
+const (
+	markOpen  = "\ue000"
+	markClose = "\ue001"
+)
+
+var markReplacer = strings.NewReplacer(markOpen, "<mark>", markClose, "</mark>")
+
+// highlightHTML 转义 s 并把标记替换为 <mark> 标签，s 中原有的标记字符被移除
+func highlightHTML(s string) string {
+	return markReplacer.Replace(html.EscapeString(s))
+}
+
+// searchIndex 模型的全文索引
+type searchIndex struct {
+	table   string          // 主表
+	ftsName string          // FTS5 虚拟表
+	pk      string          // 主键列
+	fields  []*schema.Field // 被索引的字段，顺序即 FTS 列顺序
+	like    bool            // FTS5 不可用，使用 LIKE 匹配
+}
+
+// RegisterSearch 为模型开启全文搜索，fields 为字符串字段名或列名
+// 首次创建索引表时会用主表现有数据建立索引
+//
+//	cruder.RegisterSearch(&models.User{}, "Name")
+func (t *CRUDTool) RegisterSearch(model interface{}, fields ...string) error {
+	if len(fields) == 0 {
+		return NewError(ErrKindInvalidArgument, "register_search", "至少需要一个搜索字段", nil)
+	}
+
+	sch, err := t.ParseSchema(model)
+	if err != nil {
+		return err
+	}
+	if sch.PrioritizedPrimaryField == nil {
+		return NewError(ErrKindInvalidArgument, "register_search", "全文搜索需要模型有主键", nil)
+	}
+
+	idx := &searchIndex{
+		table:   sch.Table,
+		ftsName: sch.Table + "_fts",
+		pk:      sch.PrioritizedPrimaryField.DBName,
+	}
+	for _, name := range fields {
+		field := sch.LookUpField(name)
+		if field == nil || field.DBName == "" {
+			return NewError(ErrKindInvalidArgument, "register_search", "无效的搜索字段: "+name, nil)
+		}
+		if field.DataType != schema.String {
+			return NewError(ErrKindInvalidArgument, "register_search", "搜索字段必须是字符串: "+name, nil)
+		}
+		idx.fields = append(idx.fields, field)
+	}
+
+	db := t.DB.Session(&gorm.Session{NewDB: true, SkipHooks: true})
+	created := !db.Migrator().HasTable(idx.ftsName)
+	columns := make([]string, len(idx.fields))
+	for i, field := range idx.fields {
+		columns[i] = quoteIdent(field.DBName)
+	}
+	ddl := fmt.Sprintf("CREATE VIRTUAL TABLE IF NOT EXISTS %s USING fts5(%s, tokenize = 'unicode61')",
+		quoteIdent(idx.ftsName), strings.Join(columns, ", "))
+	if err := db.Exec(ddl).Error; err != nil {
+		if !fts5Unavailable(err) {
+			return wrapDBError("register_search", "创建全文索引失败", err)
+		}
+		t.Logger.Warn(context.Background(), "SQLite 未启用 FTS5，全文搜索使用 LIKE 匹配", map[string]interface{}{
+			"table": idx.table,
+		})
+		idx.like = true
+		t.searchIndexes.Store(sch.ModelType, idx)
+		return nil
+	}
+	if created {
+		if err := idx.rebuild(db); err != nil {
+			return wrapDBError("register_search", "创建全文索引失败", err)
+		}
+	}
+
+	t.searchIndexes.Store(sch.ModelType, idx)
+	t.searchOnce.Do(func() { t.registerSearchCallbacks() })
+	return nil
+}
+
+// RebuildSearchIndex 用主表数据重建模型的全文索引
+func (t *CRUDTool) RebuildSearchIndex(ctx context.Context, model interface{}) error {
+	idx, ok := t.searchIndexFor(model)
+	if !ok {
+		return NewError(ErrKindInvalidArgument, "rebuild_search_index", "模型未开启全文搜索", nil)
+	}
+	if idx.like {
+		return nil
+	}
+	err := t.DB.WithContext(ctx).Session(&gorm.Session{SkipHooks: true}).Transaction(func(tx *gorm.DB) error {
+		return idx.rebuild(tx)
+	})
+	return wrapDBError("rebuild_search_index", "重建全文索引失败", err)
+}
+
+func (t *CRUDTool) searchIndexFor(model interface{}) (*searchIndex, bool) {
+	v, ok := t.searchIndexes.Load(modelStructType(model))
+	if !ok {
+		return nil, false
+	}
+	return v.(*searchIndex), true
+}
+
+// fts5Unavailable 错误是否因为 SQLite 编译时没有启用 FTS5
+func fts5Unavailable(err error) bool {
+	return strings.Contains(err.Error(), "no such module: fts5")
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// columnList 返回 FTS 列名列表，用于 INSERT 和 SELECT
+func (idx *searchIndex) columnList() string {
+	columns := make([]string, len(idx.fields))
+	for i, field := range idx.fields {
+		columns[i] = quoteIdent(field.DBName)
+	}
+	return strings.Join(columns, ", ")
+}
+
+// rebuild 清空并重建索引
+func (idx *searchIndex) rebuild(db *gorm.DB) error {
+	if err := db.Exec("DELETE FROM " + quoteIdent(idx.ftsName)).Error; err != nil {
+		return err
+	}
+	return db.Exec(fmt.Sprintf("INSERT INTO %s (rowid, %s) SELECT %s, %s FROM %s",
+		quoteIdent(idx.ftsName), idx.columnList(), quoteIdent(idx.pk), idx.columnList(), quoteIdent(idx.table))).Error
+}
+
+// resync 按主键从主表重新同步索引，主表中已不存在的记录会被移除
+func (idx *searchIndex) resync(db *gorm.DB, ids []interface{}) error {
+	if err := db.Exec("DELETE FROM "+quoteIdent(idx.ftsName)+" WHERE rowid IN ?", ids).Error; err != nil {
+		return err
+	}
+	return db.Exec(fmt.Sprintf("INSERT INTO %s (rowid, %s) SELECT %s, %s FROM %s WHERE %s IN ?",
+		quoteIdent(idx.ftsName), idx.columnList(), quoteIdent(idx.pk), idx.columnList(), quoteIdent(idx.table), quoteIdent(idx.pk)), ids).Error
+}
+
+// prune 移除主表中已不存在的记录
+func (idx *searchIndex) prune(db *gorm.DB) error {
+	return db.Exec(fmt.Sprintf("DELETE FROM %s WHERE rowid NOT IN (SELECT %s FROM %s)",
+		quoteIdent(idx.ftsName), quoteIdent(idx.pk), quoteIdent(idx.table))).Error
+}
+
+// touches 更新语句是否修改了被索引的列
+func (idx *searchIndex) touches(stmt *gorm.Statement) bool {
+	c, ok := stmt.Clauses["SET"]
+	if !ok {
+		return true
+	}
+	set, ok := c.Expression.(clause.Set)
+	if !ok {
+		return true
+	}
+	for _, assignment := range set {
+		for _, field := range idx.fields {
+			if assignment.Column.Name == field.DBName {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// registerSearchCallbacks 注册索引同步回调，回调与原操作在同一连接（事务）中执行，
+// 同步失败时错误会加到原操作上
+func (t *CRUDTool) registerSearchCallbacks() {
+	callbacks := t.DB.Callback()
+	_ = callbacks.Create().After("gorm:create").Register("gormtool:search_index", func(db *gorm.DB) {
+		t.syncSearchIndex(db, "create")
+	})
+	_ = callbacks.Update().After("gorm:update").Register("gormtool:search_index", func(db *gorm.DB) {
+		t.syncSearchIndex(db, "update")
+	})
+	_ = callbacks.Delete().After("gorm:delete").Register("gormtool:search_index", func(db *gorm.DB) {
+		t.syncSearchIndex(db, "delete")
+	})
+}
+
+func (t *CRUDTool) syncSearchIndex(db *gorm.DB, action string) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return
+	}
+	v, ok := t.searchIndexes.Load(db.Statement.Schema.ModelType)
+	if !ok {
+		return
+	}
+	idx := v.(*searchIndex)
+	if idx.like || (action == "update" && !idx.touches(db.Statement)) {
+		return
+	}
+
+	tx := db.Session(&gorm.Session{NewDB: true, SkipHooks: true})
+	ids, complete := primaryKeys(db.Statement)
+	var err error
+	switch {
+	case complete && len(ids) > 0:
+		err = idx.resync(tx, ids)
+	case action == "delete":
+		// 按条件删除时不知道具体主键
+		err = idx.prune(tx)
+	case !complete:
+		err = idx.rebuild(tx)
+	}
+	if err != nil {
+		db.AddError(wrapDBError("sync_search_index", "同步全文索引失败", err))
+	}
+}
+
+// primaryKeys 返回语句目标模型的主键，存在零值主键时 complete 为 false
+func primaryKeys(stmt *gorm.Statement) (ids []interface{}, complete bool) {
+	field := stmt.Schema.PrioritizedPrimaryField
+	if field == nil {
+		return nil, false
+	}
+	value := reflect.Indirect(stmt.ReflectValue)
+	collect := func(v reflect.Value) bool {
+		id, zero := field.ValueOf(stmt.Context, reflect.Indirect(v))
+		if zero {
+			return false
+		}
+		ids = append(ids, id)
+		return true
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		return ids, collect(value)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if !collect(value.Index(i)) {
+				return nil, false
+			}
+		}
+		return ids, true
+	default:
+		return nil, false
+	}
+}
+
+// matchQuery 把用户输入转换为 FTS5 查询：每个词作为前缀匹配的短语，词之间为 AND，
+// 避免输入中的引号、括号、操作符造成语法错误
+func matchQuery(search string) string {
+	terms := strings.Fields(search)
+	for i, term := range terms {
+		terms[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"*`
+	}
+	return strings.Join(terms, " ")
+}
+
+// applySearch 添加全文匹配条件，rank 为 true 时按相关度排序
+func (t *CRUDTool) applySearch(db *gorm.DB, model interface{}, search string, rank bool) (*gorm.DB, error) {
+	idx, ok := t.searchIndexFor(model)
+	if !ok {
+		return nil, invalidQuery("该资源不支持全文搜索")
+	}
+	if idx.like {
+		return idx.applyLike(db, search), nil
+	}
+	query := matchQuery(search)
+	if query == "" {
+		return db, nil
+	}
+
+	pk := clause.Column{Table: clause.CurrentTable, Name: idx.pk}
+	fts := clause.Table{Name: idx.ftsName}
+	db = db.Where(clause.Expr{
+		SQL:  "? IN (SELECT rowid FROM ? WHERE ? MATCH ?)",
+		Vars: []interface{}{pk, fts, fts, query},
+	})
+	if rank {
+		db = db.Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:  "(SELECT rank FROM ? WHERE ? MATCH ? AND rowid = ?)",
+			Vars: []interface{}{fts, fts, query, pk},
+		}})
+	}
+	return db, nil
+}
+
+// applyLike 每个词需出现在任一搜索字段中
+func (idx *searchIndex) applyLike(db *gorm.DB, search string) *gorm.DB {
+	for _, term := range strings.Fields(search) {
+		pattern := "%" + escapeLike(term) + "%"
+		ors := make([]clause.Expression, len(idx.fields))
+		for i, field := range idx.fields {
+			ors[i] = likeExpr(clause.Column{Table: clause.CurrentTable, Name: field.DBName}, pattern)
+		}
+		db = db.Where(groupExpr{logic: "OR", exprs: ors})
+	}
+	return db
+}
+
+// SearchHighlights 返回 ids 对应记录中匹配 search 的高亮片段（已转义的 HTML），键为主键和字段的 JSON 名
+func (t *CRUDTool) SearchHighlights(ctx context.Context, model interface{}, search string, ids []interface{}) (map[string]map[string]string, error) {
+	idx, ok := t.searchIndexFor(model)
+	query := matchQuery(search)
+	if !ok || query == "" || len(ids) == 0 {
+		return nil, nil
+	}
+	if idx.like {
+		return t.likeHighlights(ctx, idx, search, ids)
+	}
+
+	selects := make([]string, len(idx.fields))
+	for i := range idx.fields {
+		selects[i] = fmt.Sprintf("highlight(%s, %d, '%s', '%s')", quoteIdent(idx.ftsName), i, markOpen, markClose)
+	}
+	rows, err := t.DB.WithContext(ctx).Raw(fmt.Sprintf("SELECT rowid, %s FROM %s WHERE %s MATCH ? AND rowid IN ?",
+		strings.Join(selects, ", "), quoteIdent(idx.ftsName), quoteIdent(idx.ftsName)), query, ids).Rows()
+	if err != nil {
+		return nil, wrapDBError("search_highlight", "查询失败", err)
+	}
+	defer rows.Close()
+
+	highlights := map[string]map[string]string{}
+	for rows.Next() {
+		var rowid int64
+		values := make([]sql.NullString, len(idx.fields))
+		dest := []interface{}{&rowid}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, wrapDBError("search_highlight", "查询失败", err)
+		}
+		fields := make(map[string]string, len(idx.fields))
+		for i, field := range idx.fields {
+			// 没有匹配的列不返回
+			if strings.Contains(values[i].String, markOpen) {
+				fields[jsonName(field)] = highlightHTML(values[i].String)
+			}
+		}
+		highlights[fmt.Sprint(rowid)] = fields
+	}
+	return highlights, wrapDBError("search_highlight", "查询失败", rows.Err())
+}
+
+// likeHighlights 从主表读取搜索字段，在 Go 中标记匹配的词
+func (t *CRUDTool) likeHighlights(ctx context.Context, idx *searchIndex, search string, ids []interface{}) (map[string]map[string]string, error) {
+	rows, err := t.DB.WithContext(ctx).Raw(fmt.Sprintf("SELECT %s, %s FROM %s WHERE %s IN ?",
+		quoteIdent(idx.pk), idx.columnList(), quoteIdent(idx.table), quoteIdent(idx.pk)), ids).Rows()
+	if err != nil {
+		return nil, wrapDBError("search_highlight", "查询失败", err)
+	}
+	defer rows.Close()
+
+	terms := strings.Fields(search)
+	// 较长的词优先匹配
+	sort.Slice(terms, func(i, j int) bool { return len(terms[i]) > len(terms[j]) })
+
+	highlights := map[string]map[string]string{}
+	for rows.Next() {
+		var id interface{}
+		values := make([]sql.NullString, len(idx.fields))
+		dest := []interface{}{&id}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, wrapDBError("search_highlight", "查询失败", err)
+		}
+		fields := make(map[string]string, len(idx.fields))
+		for i, field := range idx.fields {
+			if marked, ok := markTerms(values[i].String, terms); ok {
+				fields[jsonName(field)] = marked
+			}
+		}
+		highlights[fmt.Sprint(id)] = fields
+	}
+	return highlights, wrapDBError("search_highlight", "查询失败", rows.Err())
+}
+
+// markTerms 转义 s 并用 <mark> 包裹其中出现的词（忽略大小写），ok 表示至少有一处匹配
+func markTerms(s string, terms []string) (string, bool) {
+	var b strings.Builder
+	matched := false
+	for i := 0; i < len(s); {
+		n := 0
+		for _, term := range terms {
+			if n = foldPrefix(s[i:], term); n > 0 {
+				break
+			}
+		}
+		if n > 0 {
+			b.WriteString("<mark>" + html.EscapeString(s[i:i+n]) + "</mark>")
+			i += n
+			matched = true
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		b.WriteString(html.EscapeString(s[i : i+size]))
+		i += size
+	}
+	return b.String(), matched
+}
+
+// foldPrefix s 以 term 开头（逐字符忽略大小写）时返回 s 中匹配部分的字节数，否则返回 0
+// 大小写形式的 UTF-8 长度可能不同（如 K 与开尔文符号），不能按 term 的字节数截取
+func foldPrefix(s, term string) int {
+	i := 0
+	for _, tr := range term {
+		if i >= len(s) {
+			return 0
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r != tr && !strings.EqualFold(string(r), string(tr)) {
+			return 0
+		}
+		i += size
+	}
+	return i
+}
+
+// attachHighlights 在每条结果中添加 _highlight，models 为查询结果切片指针，data 为其本身或 ProjectFields 的结果
+func (t *CRUDTool) attachHighlights(ctx context.Context, models interface{}, data interface{}, search string) (interface{}, error) {
+	sch, err := t.ParseSchema(models)
+	if err != nil || sch.PrioritizedPrimaryField == nil {
+		return data, err
+	}
+	pkKey := jsonName(sch.PrioritizedPrimaryField)
+	field := sch.PrioritizedPrimaryField
+	rows := reflect.Indirect(reflect.ValueOf(models))
+	ids := make([]interface{}, 0, rows.Len())
+	for i := 0; i < rows.Len(); i++ {
+		id, _ := field.ValueOf(ctx, reflect.Indirect(rows.Index(i)))
+		ids = append(ids, id)
+	}
+
+	highlights, err := t.SearchHighlights(ctx, models, search, ids)
+	if err != nil {
+		return nil, err
+	}
+	generic, err := toGeneric(data)
+	if err != nil {
+		return nil, err
+	}
+	items, _ := generic.([]interface{})
+	for _, item := range items {
+		if row, ok := item.(map[string]interface{}); ok {
+			row[HighlightKey] = highlights[fmt.Sprint(row[pkKey])]
+		}
+	}
+	return generic, nil
+}
+
+// toGeneric 通过 JSON 往返把数据转换为 map/slice 结构
+func toGeneric(data interface{}) (interface{}, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return nil, err
+	}
+	return generic, nil
+}
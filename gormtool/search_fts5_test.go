@@ -0,0 +1,161 @@
+// gormtool\search_fts5_test.go
+
+//go:build sqlite_fts5
+
+package gormtool
+
+import (
+	"context"
+	"testing"
+)
+
+// ftsIDs 返回全文索引中匹配 query 的 rowid
+func ftsIDs(t *testing.T, env *testEnv, query string) []uint {
+	t.Helper()
+	var ids []uint
+	err := env.tool.DB.Raw("SELECT rowid FROM test_items_fts WHERE test_items_fts MATCH ? ORDER BY rowid", matchQuery(query)).Scan(&ids).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+func newSearchEnv(t *testing.T, names ...string) (*testEnv, []testItem) {
+	t.Helper()
+	env := newTestEnv(t)
+	items := env.seed(t, names...)
+	if err := env.tool.RegisterSearch(&testItem{}, "Name"); err != nil {
+		t.Fatal(err)
+	}
+	if idx, ok := env.tool.searchIndexFor(&testItem{}); !ok || idx.like {
+		t.Fatal("使用 sqlite_fts5 标签编译时应使用 FTS5")
+	}
+	return env, items
+}
+
+// 注册时用现有数据建立索引，按相关度排序
+func TestSearchFTS5(t *testing.T) {
+	env, _ := newSearchEnv(t, "red apple", "green apple", "red red red", "pear")
+	ctx := context.Background()
+
+	var found []testItem
+	p, err := env.tool.FindPage(ctx, &found, &QueryBuilder{Search: "red"}, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Total != 2 || found[0].Name != "red red red" {
+		t.Fatalf("search red = %d, %+v", p.Total, found)
+	}
+	// 前缀匹配，多个词为 AND
+	if p, err = env.tool.FindPage(ctx, &found, &QueryBuilder{Search: "app gr"}, 1, 10); err != nil || p.Total != 1 {
+		t.Fatalf("search \"app gr\" = %+v, %v", p, err)
+	}
+	// 引号和操作符不会造成语法错误
+	if _, err = env.tool.FindPage(ctx, &found, &QueryBuilder{Search: `"red" OR (`}, 1, 10); err != nil {
+		t.Fatalf("search with operators: %v", err)
+	}
+}
+
+// 创建、更新、删除后同步索引
+func TestSearchFTS5Sync(t *testing.T) {
+	env, items := newSearchEnv(t, "apple", "pear", "plum")
+	ctx := context.Background()
+
+	if err := env.tool.CreateRecord(ctx, &testItem{Name: "apple pie"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := ftsIDs(t, env, "apple"); len(got) != 2 {
+		t.Fatalf("创建后 apple = %v", got)
+	}
+
+	err := env.tool.UpdateRecord(ctx, &testItem{}, items[1].ID, func(m interface{}) error {
+		m.(*testItem).Name = "apple tart"
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ftsIDs(t, env, "pear"); len(got) != 0 {
+		t.Fatalf("更新后 pear = %v", got)
+	}
+	if got := ftsIDs(t, env, "tart"); len(got) != 1 || got[0] != items[1].ID {
+		t.Fatalf("更新后 tart = %v", got)
+	}
+
+	// 按条件更新不知道主键，重建索引
+	if err := env.tool.DB.Model(&testItem{}).Where("name = ?", "plum").Update("name", "cherry").Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := ftsIDs(t, env, "cherry"); len(got) != 1 {
+		t.Fatalf("按条件更新后 cherry = %v", got)
+	}
+
+	// 软删除保留在索引中，由主表条件过滤
+	if err := env.tool.SoftDelete(ctx, &testItem{}, items[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := ftsIDs(t, env, "apple"); len(got) != 3 {
+		t.Fatalf("软删除后索引 apple = %v", got)
+	}
+	var found []testItem
+	if p, err := env.tool.FindPage(ctx, &found, &QueryBuilder{Search: "apple"}, 1, 10); err != nil || p.Total != 2 {
+		t.Fatalf("软删除后 search apple = %+v, %v", p, err)
+	}
+
+	if err := env.tool.HardDelete(ctx, &testItem{}, items[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := ftsIDs(t, env, "apple"); len(got) != 2 {
+		t.Fatalf("删除后 apple = %v", got)
+	}
+
+	// 按条件删除不知道主键，清理主表中已不存在的记录
+	if err := env.tool.DB.Unscoped().Where("name LIKE ?", "apple%").Delete(&testItem{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := ftsIDs(t, env, "apple"); len(got) != 0 {
+		t.Fatalf("按条件删除后 apple = %v", got)
+	}
+}
+
+// 绕过 GORM 写入的数据在重建后可以搜索到
+func TestRebuildSearchIndex(t *testing.T) {
+	env, _ := newSearchEnv(t, "apple")
+	ctx := context.Background()
+
+	if err := env.tool.DB.Exec("INSERT INTO test_items (name, score) VALUES ('apple raw', 0)").Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := ftsIDs(t, env, "raw"); len(got) != 0 {
+		t.Fatalf("重建前 raw = %v", got)
+	}
+	if err := env.tool.RebuildSearchIndex(ctx, &testItem{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := ftsIDs(t, env, "raw"); len(got) != 1 {
+		t.Fatalf("重建后 raw = %v", got)
+	}
+	if err := env.tool.RebuildSearchIndex(ctx, &testOwner{}); KindOf(err) != ErrKindInvalidArgument {
+		t.Fatalf("未注册模型 err = %v", err)
+	}
+}
+
+// 高亮片段转义字段内容
+func TestSearchFTS5Highlights(t *testing.T) {
+	env, items := newSearchEnv(t, "<img src=x onerror=alert(1)> red", "Red apple")
+	ctx := context.Background()
+
+	highlights, err := env.tool.SearchHighlights(ctx, &testItem{}, "red", []interface{}{items[0].ID, items[1].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"1": "&lt;img src=x onerror=alert(1)&gt; <mark>red</mark>",
+		"2": "<mark>Red</mark> apple",
+	}
+	for id, w := range want {
+		if got := highlights[id]["Name"]; got != w {
+			t.Errorf("highlight %s = %q, want %q", id, got, w)
+		}
+	}
+}
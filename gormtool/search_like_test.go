@@ -0,0 +1,65 @@
+// gormtool\search_like_test.go
+
+//go:build !sqlite_fts5
+
+package gormtool
+
+import (
+	"context"
+	"testing"
+)
+
+// 未使用 sqlite_fts5 标签编译时，搜索使用 LIKE 匹配
+func TestSearchLikeFallback(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	items := env.seed(t, "Red apple", "green apple", "red pepper", "100%", "<img src=x onerror=alert(1)> red")
+
+	if err := env.tool.RegisterSearch(&testItem{}, "Name"); err != nil {
+		t.Fatal(err)
+	}
+	if idx, ok := env.tool.searchIndexFor(&testItem{}); !ok || !idx.like {
+		t.Fatal("未启用 FTS5 时应使用 LIKE 匹配")
+	}
+
+	tests := map[string]int{
+		"apple":     2,
+		"RED":       3,
+		"red apple": 1,
+		"%":         1,
+		"banana":    0,
+	}
+	for search, want := range tests {
+		var found []testItem
+		p, err := env.tool.FindPage(ctx, &found, &QueryBuilder{Search: search}, 1, 10)
+		if err != nil {
+			t.Fatalf("search %q: %v", search, err)
+		}
+		if p.Total != want {
+			t.Errorf("search %q: total = %d, want %d", search, p.Total, want)
+		}
+	}
+
+	// 写入不需要同步索引
+	if err := env.tool.CreateRecord(ctx, &testItem{Name: "apple pie"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.tool.RebuildSearchIndex(ctx, &testItem{}); err != nil {
+		t.Fatal(err)
+	}
+
+	highlights, err := env.tool.SearchHighlights(ctx, &testItem{}, "red apple", []interface{}{items[0].ID, items[2].ID, items[4].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"1": "<mark>Red</mark> <mark>apple</mark>",
+		"3": "<mark>red</mark> pepper",
+		"5": "&lt;img src=x onerror=alert(1)&gt; <mark>red</mark>",
+	}
+	for id, w := range want {
+		if got := highlights[id]["Name"]; got != w {
+			t.Errorf("highlight %s = %q, want %q", id, got, w)
+		}
+	}
+}
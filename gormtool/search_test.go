@@ -0,0 +1,41 @@
+// gormtool\search_test.go
+package gormtool
+
+import (
+	"testing"
+)
+
+func TestMarkTerms(t *testing.T) {
+	tests := []struct {
+		s     string
+		terms []string
+		want  string
+	}{
+		{"苹果和Apple", []string{"apple", "苹果"}, "<mark>苹果</mark>和<mark>Apple</mark>"},
+		{"<img src=x onerror=alert(1)> red", []string{"red"}, "&lt;img src=x onerror=alert(1)&gt; <mark>red</mark>"},
+		{"a<b> & c", []string{"<b>"}, "a<mark>&lt;b&gt;</mark> &amp; c"},
+		// 开尔文符号 K（3 字节）与 k 忽略大小写相等
+		{"Kelvin", []string{"kelvin"}, "<mark>Kelvin</mark>"},
+		{"straße", []string{"STRASSE"}, ""},
+	}
+	for _, tt := range tests {
+		got, ok := markTerms(tt.s, tt.terms)
+		if tt.want == "" {
+			if ok {
+				t.Errorf("markTerms(%q) = %q, want no match", tt.s, got)
+			}
+			continue
+		}
+		if !ok || got != tt.want {
+			t.Errorf("markTerms(%q) = %q, %v; want %q", tt.s, got, ok, tt.want)
+		}
+	}
+}
+
+func TestHighlightHTML(t *testing.T) {
+	in := "<script>" + markOpen + "red" + markClose + "</script>"
+	want := "&lt;script&gt;<mark>red</mark>&lt;/script&gt;"
+	if got := highlightHTML(in); got != want {
+		t.Fatalf("highlightHTML = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,479 @@
+// gormtool\service.go
+package gormtool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 服务层：与传输协议无关的 CRUD 操作
+// 只接收 context.Context 和类型化参数，返回结果和 *Error，
+// 可在 HTTP handler、后台任务、命令行中复用，HTTP 响应由 handler.go 中的 gin 适配层负责
+
+// FindOptions 单条查询选项
+type FindOptions struct {
+	Preloads []string
+	Unscoped bool // 包含已软删除的记录，不走缓存
+	NoCache  bool // 跳过缓存
+
+	// 稀疏字段，见 QueryBuilder.Select；指定后只查询这些列，不读写缓存
+	Select          []string
+	SelectRelations map[string][]string
+}
+
+// ApplyFunc 更新前修改已加载的模型
+type ApplyFunc func(model interface{}) error
+
+// logService 记录服务层操作日志，失败时附带 error_type
+func (t *CRUDTool) logService(ctx context.Context, operation string, model interface{}, start time.Time, err error, fields map[string]interface{}) {
+	if err != nil {
+		if fields == nil {
+			fields = map[string]interface{}{}
+		}
+		fields["error_type"] = string(KindOf(err))
+	}
+	t.LogOperation(ctx, operation, model, time.Since(start), err, fields)
+}
+
+// FindByID 根据ID查询单条记录，返回结果是否来自缓存
+// 走缓存时同一条记录的并发未命中只有一个请求查询数据库，其余请求等待并共享结果；
+// 记录不存在（包括已软删除）时缓存 NegativeCacheTTL，期间直接返回不存在
+func (t *CRUDTool) FindByID(ctx context.Context, model interface{}, id uint, opts FindOptions) (cached bool, err error) {
+	start := time.Now()
+	operation := "get_by_id"
+	if opts.Unscoped {
+		operation = "get_by_id_soft_delete"
+	}
+	ctx = t.startOperation(ctx, operation, model)
+	coalesced := false
+
+	defer func() {
+		t.logService(ctx, operation, model, start, err, map[string]interface{}{
+			"id":        id,
+			"preloads":  opts.Preloads,
+			"cached":    cached,
+			"coalesced": coalesced,
+		})
+	}()
+
+	sparse := &QueryBuilder{Preloads: opts.Preloads, Select: opts.Select, SelectRelations: opts.SelectRelations}
+	useCache := !opts.Unscoped && !opts.NoCache && !sparse.hasSelection()
+	if !useCache {
+		if err = t.loadByID(ctx, model, id, opts, sparse); err != nil {
+			err = wrapDBError(operation, "查询失败", err)
+			return false, err
+		}
+		return false, nil
+	}
+
+	cacheKey := t.GenerateCacheKey(model, id)
+	if data, ok := t.getCacheBytes(ctx, cacheKey); ok {
+		if bytes.Equal(data, negativeCacheValue) {
+			err = wrapDBError(operation, "查询失败", gorm.ErrRecordNotFound)
+			return true, err
+		}
+		if json.Unmarshal(data, model) == nil && !t.refreshEarly(ctx, cacheKey, model) {
+			return true, nil
+		}
+	}
+
+	// 共享的查询加载到新的实例上，所有调用者从结果反序列化
+	flightKey := cacheKey + "|" + strings.Join(opts.Preloads, ",")
+	data, coalesced, err := t.flight.Do(ctx, flightKey, func(ctx context.Context) ([]byte, error) {
+		fresh := reflect.New(reflect.TypeOf(model).Elem()).Interface()
+		loadStart := time.Now()
+		if err := t.loadByID(ctx, fresh, id, opts, sparse); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				t.setNegativeCache(ctx, cacheKey)
+			}
+			return nil, err
+		}
+		t.loadDurations.Store(fmt.Sprintf("%T", model), time.Since(loadStart))
+
+		data, err := json.Marshal(fresh)
+		if err != nil {
+			return nil, err
+		}
+		t.setCacheBytes(ctx, cacheKey, data)
+		return data, nil
+	})
+	if err == nil {
+		err = json.Unmarshal(data, model)
+	}
+	if err != nil {
+		err = wrapDBError(operation, "查询失败", err)
+		return false, err
+	}
+	return false, nil
+}
+
+// loadByID 从数据库加载单条记录
+func (t *CRUDTool) loadByID(ctx context.Context, model interface{}, id uint, opts FindOptions, sparse *QueryBuilder) error {
+	db := t.DB.WithContext(ctx)
+	if opts.Unscoped {
+		db = db.Unscoped()
+	}
+	if sparse.hasSelection() {
+		r, err := t.newFieldResolver(model)
+		if err != nil {
+			return err
+		}
+		if db, err = t.applySelectAndPreloads(db, r, sparse); err != nil {
+			return err
+		}
+	} else {
+		for _, preload := range opts.Preloads {
+			db = db.Preload(preload)
+		}
+	}
+	return db.First(model, id).Error
+}
+
+// FindPage 使用查询构建器分页查询，models 为切片指针
+// 设置了 ListCacheTTL 时结果会被缓存，见 cache_list.go
+func (t *CRUDTool) FindPage(ctx context.Context, models interface{}, qb *QueryBuilder, page, pageSize int) (p *Pagination, err error) {
+	start := time.Now()
+	ctx = t.startOperation(ctx, "get_by_query_builder", models)
+	page, pageSize = normalizePage(page, pageSize)
+	cached := false
+
+	defer func() {
+		fields := map[string]interface{}{
+			"page":     page,
+			"pagesize": pageSize,
+			"cached":   cached,
+		}
+		if qb != nil && qb.Search != "" {
+			fields["search"] = qb.Search
+		}
+		t.logService(ctx, "get_by_query_builder", models, start, err, fields)
+	}()
+
+	params := map[string]interface{}{"qb": qb, "page": page, "pagesize": pageSize}
+	p, cached, err = t.cachedList(ctx, "page", models, params, qb.preloadNames(), func() (*Pagination, error) {
+		db, err := t.BuildQuery(t.DB.WithContext(ctx), models, qb)
+		if err != nil {
+			return nil, err
+		}
+
+		// 获取总数
+		var total int64
+		if err := db.Model(models).Count(&total).Error; err != nil {
+			return nil, err
+		}
+
+		// 分页查询
+		offset := (page - 1) * pageSize
+		if err := db.Limit(pageSize).Offset(offset).Find(models).Error; err != nil {
+			return nil, err
+		}
+
+		return &Pagination{
+			Page:     page,
+			PageSize: pageSize,
+			Total:    int(total),
+		}, nil
+	})
+	if err != nil {
+		err = wrapDBError("get_by_query_builder", "查询失败", err)
+		return nil, err
+	}
+	return p, nil
+}
+
+// CreateRecord 创建记录，relations 中的关联字段会在同一事务中 Replace
+func (t *CRUDTool) CreateRecord(ctx context.Context, model interface{}, relations ...string) (err error) {
+	start := time.Now()
+	ctx = t.startOperation(ctx, "create", model)
+
+	defer func() {
+		t.logService(ctx, "create", model, start, err, map[string]interface{}{
+			"relations": relations,
+		})
+	}()
+
+	err = t.WithTransaction(ctx, func(tx *gorm.DB) error {
+		// 先创建主记录
+		if err := tx.Create(model).Error; err != nil {
+			return err
+		}
+		// 逐条追加关联
+		return replaceRelations(tx, model, relations)
+	})
+	if err != nil {
+		err = wrapDBError("create", "创建失败", err)
+		return err
+	}
+
+	// 清除该 ID 的不存在缓存（客户端指定 ID 创建时），使相关列表失效
+	t.invalidateRows(ctx, model)
+	t.invalidateRelations(ctx, model, relations...)
+	return nil
+}
+
+// UpdateRecord 加载记录后调用 apply 修改并保存，relations 中的关联字段会被 Replace，成功后清除缓存
+func (t *CRUDTool) UpdateRecord(ctx context.Context, model interface{}, id uint, apply ApplyFunc, relations ...string) (err error) {
+	start := time.Now()
+	ctx = t.startOperation(ctx, "update_by_id", model)
+
+	defer func() {
+		t.logService(ctx, "update_by_id", model, start, err, map[string]interface{}{
+			"id":        id,
+			"relations": relations,
+		})
+	}()
+
+	// 先检查记录是否存在
+	if err = t.DB.WithContext(ctx).First(model, id).Error; err != nil {
+		err = wrapDBError("update_by_id", "查询失败", err)
+		return err
+	}
+
+	if apply != nil {
+		if err = apply(model); err != nil {
+			err = wrapDBError("update_by_id", "更新失败", err)
+			return err
+		}
+	}
+
+	err = t.WithTransaction(ctx, func(tx *gorm.DB) error {
+		if err := tx.Save(model).Error; err != nil {
+			return err
+		}
+		return replaceRelations(tx, model, relations)
+	})
+	if err != nil {
+		err = wrapDBError("update_by_id", "更新失败", err)
+		return err
+	}
+
+	// 清除缓存
+	t.InvalidateRecord(ctx, model, id)
+	t.invalidateRelations(ctx, model, relations...)
+	return nil
+}
+
+// SoftDelete 软删除
+func (t *CRUDTool) SoftDelete(ctx context.Context, model interface{}, id uint) error {
+	return t.deleteRecord(ctx, "soft_delete", t.DB.WithContext(ctx), model, id)
+}
+
+// HardDelete 硬删除
+func (t *CRUDTool) HardDelete(ctx context.Context, model interface{}, id uint) error {
+	return t.deleteRecord(ctx, "hard_delete", t.DB.WithContext(ctx).Unscoped(), model, id)
+}
+
+func (t *CRUDTool) deleteRecord(ctx context.Context, operation string, db *gorm.DB, model interface{}, id uint) (err error) {
+	start := time.Now()
+	ctx = t.startOperation(ctx, operation, model)
+	db = db.WithContext(ctx)
+
+	defer func() {
+		t.logService(ctx, operation, model, start, err, map[string]interface{}{
+			"id": id,
+		})
+	}()
+
+	result := db.Delete(model, id)
+	if result.Error != nil {
+		err = wrapDBError(operation, "删除失败", result.Error)
+		return err
+	}
+	if result.RowsAffected == 0 {
+		err = wrapDBError(operation, "删除失败", gorm.ErrRecordNotFound)
+		return err
+	}
+
+	// 清除缓存
+	t.InvalidateRecord(ctx, model, id)
+	return nil
+}
+
+// Restore 恢复软删除的记录
+func (t *CRUDTool) Restore(ctx context.Context, model interface{}, id uint) (err error) {
+	start := time.Now()
+	ctx = t.startOperation(ctx, "restore", model)
+
+	defer func() {
+		t.logService(ctx, "restore", model, start, err, map[string]interface{}{
+			"id": id,
+		})
+	}()
+
+	result := t.DB.WithContext(ctx).Unscoped().Model(model).Where("id = ?", id).Update("deleted_at", nil)
+	if result.Error != nil {
+		err = wrapDBError("restore", "恢复失败", result.Error)
+		return err
+	}
+	if result.RowsAffected == 0 {
+		err = wrapDBError("restore", "恢复失败", gorm.ErrRecordNotFound)
+		return err
+	}
+
+	// 清除软删除期间缓存的不存在
+	t.InvalidateRecord(ctx, model, id)
+	return nil
+}
+
+// Batch 批量操作，models 为切片指针，operation 取值见 BatchCreate 等常量，返回受影响行数，成功后清除涉及记录的缓存
+func (t *CRUDTool) Batch(ctx context.Context, models interface{}, operation string) (affected int64, err error) {
+	start := time.Now()
+	name := batchOperationName(operation)
+	ctx = t.startOperation(ctx, name, models)
+
+	defer func() {
+		t.logService(ctx, name, models, start, err, map[string]interface{}{
+			"affected": affected,
+		})
+	}()
+
+	db := t.DB.WithContext(ctx)
+	var result *gorm.DB
+	switch operation {
+	case BatchCreate:
+		result = db.Create(models)
+	case BatchUpdate:
+		result = db.Save(models)
+	case BatchSoftDelete:
+		result = db.Delete(models)
+	case BatchHardDelete:
+		result = db.Unscoped().Delete(models)
+	default:
+		err = NewError(ErrKindInvalidArgument, name, "不支持的批量操作", nil)
+		return 0, err
+	}
+
+	if result.Error != nil {
+		err = wrapDBError(name, "批量操作失败", result.Error)
+		return 0, err
+	}
+
+	// 清除涉及记录的缓存，包括新建记录 ID 上的不存在缓存
+	t.invalidateRows(ctx, models)
+	return result.RowsAffected, nil
+}
+
+// batchOperationName 返回批量操作在日志、指标和 span 中的名称
+// 不支持的操作统一为 batch_invalid，客户端传入的任意值不会产生新的指标序列
+func batchOperationName(operation string) string {
+	switch operation {
+	case BatchCreate, BatchUpdate, BatchSoftDelete, BatchHardDelete:
+		return "batch_" + operation
+	}
+	return "batch_invalid"
+}
+
+// FindRelated 获取关联记录，result 为关联模型切片指针
+func (t *CRUDTool) FindRelated(ctx context.Context, model interface{}, id uint, associationName string, result interface{}) (err error) {
+	start := time.Now()
+	ctx = t.startOperation(ctx, "get_related", model)
+
+	defer func() {
+		t.logService(ctx, "get_related", model, start, err, map[string]interface{}{
+			"id":          id,
+			"association": associationName,
+		})
+	}()
+
+	// 先获取主记录
+	if err = t.DB.WithContext(ctx).First(model, id).Error; err != nil {
+		err = wrapDBError("get_related", "查询失败", err)
+		return err
+	}
+
+	if err = t.DB.WithContext(ctx).Model(model).Association(associationName).Find(result); err != nil {
+		err = wrapDBError("get_related", "获取关联记录失败", err)
+		return err
+	}
+	return nil
+}
+
+// AppendRelation 添加关联关系
+func (t *CRUDTool) AppendRelation(ctx context.Context, model interface{}, id uint, associationName string, related interface{}) (err error) {
+	start := time.Now()
+	ctx = t.startOperation(ctx, "add_relation", model)
+
+	defer func() {
+		t.logService(ctx, "add_relation", model, start, err, map[string]interface{}{
+			"id":          id,
+			"association": associationName,
+		})
+	}()
+
+	// 先获取主记录
+	if err = t.DB.WithContext(ctx).First(model, id).Error; err != nil {
+		err = wrapDBError("add_relation", "查询失败", err)
+		return err
+	}
+
+	if err = t.DB.WithContext(ctx).Model(model).Association(associationName).Append(related); err != nil {
+		err = wrapDBError("add_relation", "添加关联失败", err)
+		return err
+	}
+
+	t.InvalidateRecord(ctx, model, id)
+	t.invalidateRelations(ctx, model, associationName)
+	return nil
+}
+
+// PatchRecord 部分更新，只更新 fields 中出现的字段，字段名可以是结构体字段名或列名，成功后清除缓存
+func (t *CRUDTool) PatchRecord(ctx context.Context, model interface{}, id uint, fields map[string]interface{}) (err error) {
+	start := time.Now()
+	ctx = t.startOperation(ctx, "patch_by_id", model)
+
+	defer func() {
+		t.logService(ctx, "patch_by_id", model, start, err, map[string]interface{}{
+			"id": id,
+		})
+	}()
+
+	sch, err := t.ParseSchema(model)
+	if err != nil {
+		err = wrapDBError("patch_by_id", "更新失败", err)
+		return err
+	}
+
+	updates := make(map[string]interface{}, len(fields))
+	for name, value := range fields {
+		field := sch.LookUpField(name)
+		if field == nil || field.DBName == "" || field.PrimaryKey || !field.Updatable ||
+			field.AutoCreateTime > 0 || field.Name == "DeletedAt" {
+			err = NewError(ErrKindInvalidArgument, "patch_by_id", fmt.Sprintf("字段不可更新: %s", name), nil)
+			return err
+		}
+		updates[field.DBName] = value
+	}
+	if len(updates) == 0 {
+		err = NewError(ErrKindInvalidArgument, "patch_by_id", "没有需要更新的字段", nil)
+		return err
+	}
+
+	// 先检查记录是否存在
+	if err = t.DB.WithContext(ctx).First(model, id).Error; err != nil {
+		err = wrapDBError("patch_by_id", "查询失败", err)
+		return err
+	}
+
+	if err = t.DB.WithContext(ctx).Model(model).Updates(updates).Error; err != nil {
+		err = wrapDBError("patch_by_id", "更新失败", err)
+		return err
+	}
+
+	// 重新加载，返回更新后的完整记录
+	if err = t.DB.WithContext(ctx).First(model, id).Error; err != nil {
+		err = wrapDBError("patch_by_id", "查询失败", err)
+		return err
+	}
+
+	// 清除缓存
+	t.InvalidateRecord(ctx, model, id)
+	return nil
+}
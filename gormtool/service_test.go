@@ -0,0 +1,59 @@
+// gormtool\service_test.go
+package gormtool
+
+import (
+	"context"
+	"testing"
+)
+
+// 不存在的记录缓存 NegativeCacheTTL，期间不再查询数据库；创建该 ID 后清除
+func TestFindByIDNegativeCache(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	var item testItem
+	cached, err := env.tool.FindByID(ctx, &item, 42, FindOptions{})
+	if !IsNotFound(err) || cached {
+		t.Fatalf("第一次查询 = %v, %v; want not found from db", cached, err)
+	}
+	cached, err = env.tool.FindByID(ctx, &item, 42, FindOptions{})
+	if !IsNotFound(err) || !cached {
+		t.Fatalf("第二次查询 = %v, %v; want not found from cache", cached, err)
+	}
+	if got := env.queries.Load(); got != 1 {
+		t.Fatalf("数据库查询 %d 次, want 1", got)
+	}
+	ttl, err := env.tool.Cache.TTL(ctx, env.tool.GenerateCacheKey(&testItem{}, uint(42)))
+	if err != nil || ttl <= 0 || ttl > NegativeCacheTTL {
+		t.Fatalf("不存在缓存 TTL = %v, %v; want (0, %v]", ttl, err, NegativeCacheTTL)
+	}
+
+	if err := env.tool.CreateRecord(ctx, &testItem{ID: 42, Name: "new"}); err != nil {
+		t.Fatal(err)
+	}
+	item = testItem{}
+	if _, err := env.tool.FindByID(ctx, &item, 42, FindOptions{}); err != nil || item.Name != "new" {
+		t.Fatalf("创建后查询 = %+v, %v", item, err)
+	}
+}
+
+// 软删除后按 ID 查询返回不存在，缓存被清除
+func TestFindByIDAfterSoftDelete(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	items := env.seed(t, "a")
+
+	var item testItem
+	if _, err := env.tool.FindByID(ctx, &item, items[0].ID, FindOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.tool.SoftDelete(ctx, &testItem{}, items[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.tool.FindByID(ctx, &item, items[0].ID, FindOptions{}); !IsNotFound(err) {
+		t.Fatalf("软删除后查询 err = %v, want not found", err)
+	}
+	if _, err := env.tool.FindByID(ctx, &item, items[0].ID, FindOptions{Unscoped: true}); err != nil {
+		t.Fatalf("Unscoped 查询 err = %v", err)
+	}
+}
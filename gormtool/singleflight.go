@@ -0,0 +1,66 @@
+// gormtool\singleflight.go
+package gormtool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// flightTimeout 合并查询的超时时间
+// 共享的查询不使用任何一个调用者的 context：发起查询的请求断开或超时后，其他等待者仍能拿到结果
+const flightTimeout = 30 * time.Second
+
+// flightGroup 按键合并并发调用：同一个键同时只执行一次 fn，其余调用等待并共享结果
+// （与 golang.org/x/sync/singleflight 相同的思路，只保留这里需要的部分）
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done chan struct{}
+	val  []byte
+	err  error
+}
+
+// Do 执行并返回 fn 的结果，joined 表示加入了已在执行的调用
+// fn 在独立的 goroutine 中执行，使用去掉取消信号并带 flightTimeout 的 ctx；
+// 每个调用者只等待到自己的 ctx 结束，提前返回时 fn 继续执行并写入结果供其他调用者使用。
+// fn 发生 panic 时所有调用者收到错误
+func (g *flightGroup) Do(ctx context.Context, key string, fn func(ctx context.Context) ([]byte, error)) (val []byte, joined bool, err error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	call, joined := g.calls[key]
+	if !joined {
+		call = &flightCall{done: make(chan struct{})}
+		g.calls[key] = call
+		go g.run(ctx, key, call, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.val, joined, call.err
+	case <-ctx.Done():
+		return nil, joined, ctx.Err()
+	}
+}
+
+func (g *flightGroup) run(ctx context.Context, key string, call *flightCall, fn func(ctx context.Context) ([]byte, error)) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), flightTimeout)
+	defer func() {
+		cancel()
+		if r := recover(); r != nil {
+			call.val, call.err = nil, fmt.Errorf("gormtool: 合并查询 panic: %v", r)
+		}
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(call.done)
+	}()
+	call.val, call.err = fn(ctx)
+}
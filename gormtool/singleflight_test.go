@@ -0,0 +1,200 @@
+// gormtool\singleflight_test.go
+package gormtool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 同一条记录的并发缓存未命中只查询一次数据库
+func TestFindByIDCoalescesConcurrentMisses(t *testing.T) {
+	env := newTestEnv(t)
+	items := env.seed(t, "a")
+	env.queryDelay = 50 * time.Millisecond
+
+	const n = 10
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make([]error, n)
+	results := make([]testItem, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			_, errs[i] = env.tool.FindByID(context.Background(), &results[i], items[0].ID, FindOptions{})
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	for i := 0; i < n; i++ {
+		if errs[i] != nil {
+			t.Fatalf("第 %d 个请求: %v", i, errs[i])
+		}
+		if results[i].Name != "a" {
+			t.Fatalf("第 %d 个请求: Name = %q", i, results[i].Name)
+		}
+	}
+	if got := env.queries.Load(); got != 1 {
+		t.Fatalf("数据库查询 %d 次, want 1", got)
+	}
+
+	// 之后的请求命中缓存
+	var item testItem
+	cached, err := env.tool.FindByID(context.Background(), &item, items[0].ID, FindOptions{})
+	if err != nil || !cached {
+		t.Fatalf("FindByID = %v, %v; want cached", cached, err)
+	}
+}
+
+// fn panic 时所有调用者收到错误
+func TestFlightGroupPanic(t *testing.T) {
+	var g flightGroup
+	entered := make(chan struct{})
+	release := make(chan struct{})
+
+	var leaderErr, waiterErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, _, leaderErr = g.Do(context.Background(), "k", func(context.Context) ([]byte, error) {
+			close(entered)
+			<-release
+			panic("boom")
+		})
+	}()
+
+	<-entered
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, _, waiterErr = g.Do(context.Background(), "k", func(context.Context) ([]byte, error) {
+			return nil, errors.New("不应执行")
+		})
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for _, err := range []error{leaderErr, waiterErr} {
+		if err == nil || err.Error() != "gormtool: 合并查询 panic: boom" {
+			t.Fatalf("err = %v", err)
+		}
+	}
+}
+
+// 发起查询的请求被取消后，共享的查询继续执行，等待者拿到结果
+func TestFlightGroupLeaderCanceled(t *testing.T) {
+	var g flightGroup
+	entered := make(chan struct{})
+	release := make(chan struct{})
+
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderDone := make(chan error, 1)
+	go func() {
+		_, _, err := g.Do(leaderCtx, "k", func(ctx context.Context) ([]byte, error) {
+			close(entered)
+			<-release
+			return []byte("v"), ctx.Err()
+		})
+		leaderDone <- err
+	}()
+	<-entered
+
+	waiterDone := make(chan struct{})
+	var val []byte
+	var joined bool
+	var waiterErr error
+	go func() {
+		defer close(waiterDone)
+		val, joined, waiterErr = g.Do(context.Background(), "k", nil)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	cancel()
+	if err := <-leaderDone; !errors.Is(err, context.Canceled) {
+		t.Fatalf("leader err = %v, want context.Canceled", err)
+	}
+	close(release)
+	<-waiterDone
+	if waiterErr != nil || string(val) != "v" || !joined {
+		t.Fatalf("waiter = %q, %v, %v", val, joined, waiterErr)
+	}
+}
+
+// 等待者的 ctx 结束时不再等待
+func TestFlightGroupWaiterCanceled(t *testing.T) {
+	var g flightGroup
+	release := make(chan struct{})
+	defer close(release)
+	go g.Do(context.Background(), "k", func(context.Context) ([]byte, error) {
+		<-release
+		return nil, nil
+	})
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, _, err := g.Do(ctx, "k", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+// 第一个请求被取消时，并发的 FindByID 仍然返回记录
+func TestFindByIDLeaderCanceled(t *testing.T) {
+	env := newTestEnv(t)
+	items := env.seed(t, "a")
+	env.queryDelay = 50 * time.Millisecond
+
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderDone := make(chan error, 1)
+	go func() {
+		var item testItem
+		_, err := env.tool.FindByID(leaderCtx, &item, items[0].ID, FindOptions{})
+		leaderDone <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	waiterDone := make(chan error, 1)
+	var item testItem
+	go func() {
+		_, err := env.tool.FindByID(context.Background(), &item, items[0].ID, FindOptions{})
+		waiterDone <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	if err := <-leaderDone; !errors.Is(err, context.Canceled) {
+		t.Fatalf("leader err = %v, want context.Canceled", err)
+	}
+	if err := <-waiterDone; err != nil || item.Name != "a" {
+		t.Fatalf("waiter = %+v, %v", item, err)
+	}
+	if got := env.queries.Load(); got != 1 {
+		t.Fatalf("数据库查询 %d 次, want 1", got)
+	}
+}
+
+func TestCacheTTLJitter(t *testing.T) {
+	tool := &CRUDTool{CacheTTLJitter: 0.1}
+	min, max := time.Duration(float64(CacheTTL)*0.9), time.Duration(float64(CacheTTL)*1.1)
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		ttl := tool.cacheTTL()
+		if ttl < min || ttl > max {
+			t.Fatalf("cacheTTL = %v, want within [%v, %v]", ttl, min, max)
+		}
+		seen[ttl] = true
+	}
+	if len(seen) < 2 {
+		t.Fatal("cacheTTL 没有随机抖动")
+	}
+	if ttl := (&CRUDTool{}).cacheTTL(); ttl != CacheTTL {
+		t.Fatalf("未设置抖动时 cacheTTL = %v, want %v", ttl, CacheTTL)
+	}
+}
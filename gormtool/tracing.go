@@ -0,0 +1,260 @@
+// gormtool\tracing.go
+package gormtool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// 链路追踪（OpenTelemetry）
+// 只依赖 OpenTelemetry API，导出器（stdout、OTLP 等）由调用方配置 TracerProvider。
+//
+//	HTTP 请求      TracingMiddleware，从 W3C traceparent/tracestate 请求头继续上游的链路，并在响应头中返回
+//	CRUDTool 操作  span 名称与 LogOperation 的 operation 相同（create、get_by_id ...）
+//	SQL 语句       RegisterTracing 注册的 GORM 回调，每条语句一个 span
+//	Redis 命令     TraceRedis 添加的 go-redis Hook
+//
+// LogOperation 的日志字段中会带上 trace_id 和 span_id。
+
+// TracerName 创建 Tracer 使用的名称
+const TracerName = "github.com/studieren/eco_back/gormtool"
+
+// tracePropagator W3C Trace Context 和 Baggage
+var tracePropagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// tracer 返回 TracerProvider（未设置时为 otel 全局 TracerProvider）的 Tracer
+func (t *CRUDTool) tracer() trace.Tracer {
+	tp := t.TracerProvider
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return tp.Tracer(TracerName)
+}
+
+type operationSpanKey struct{}
+
+// operationSpan 一个 CRUDTool 操作的 span，由 LogOperation 结束
+type operationSpan struct {
+	operation string
+	span      trace.Span
+	once      sync.Once
+}
+
+// startOperation 开始一个操作 span，返回的 context 应传给该操作内的数据库和缓存调用
+func (t *CRUDTool) startOperation(ctx context.Context, operation string, model interface{}) context.Context {
+	ctx, span := t.tracer().Start(ctx, operation)
+	if span.IsRecording() {
+		span.SetAttributes(
+			attribute.String("gormtool.operation", operation),
+			attribute.String("gormtool.model", t.metricModel(model)),
+		)
+	}
+	return context.WithValue(ctx, operationSpanKey{}, &operationSpan{operation: operation, span: span})
+}
+
+// finishOperation 结束 ctx 中同名的操作 span，重复调用无效
+func finishOperation(ctx context.Context, operation string, err error, fields map[string]interface{}) {
+	op, ok := ctx.Value(operationSpanKey{}).(*operationSpan)
+	if !ok || op.operation != operation {
+		return
+	}
+	op.once.Do(func() {
+		if op.span.IsRecording() {
+			for k, v := range fields {
+				op.span.SetAttributes(attribute.String("gormtool."+k, fmt.Sprint(v)))
+			}
+			if err != nil {
+				op.span.RecordError(err)
+				op.span.SetStatus(codes.Error, err.Error())
+				op.span.SetAttributes(attribute.String("gormtool.error_type", string(KindOf(err))))
+			}
+		}
+		op.span.End()
+	})
+}
+
+// traceFields 返回 ctx 中 span 的 trace_id 和 span_id，没有 span 时返回 nil
+func traceFields(ctx context.Context) map[string]interface{} {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+	return map[string]interface{}{
+		"trace_id": sc.TraceID().String(),
+		"span_id":  sc.SpanID().String(),
+	}
+}
+
+// TracingMiddleware 为每个请求创建 span，span 名称为 "方法 路由模板"（如 GET /users/:id）
+// 请求带有 traceparent 时作为上游链路的子 span，响应头中返回本次请求的 traceparent
+func (t *CRUDTool) TracingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := tracePropagator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx, span := t.tracer().Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", c.Request.URL.Path),
+				attribute.String("client.address", c.ClientIP()),
+			))
+		defer span.End()
+		if id := RequestIDFromContext(ctx); id != "" {
+			span.SetAttributes(attribute.String("http.request.id", id))
+		}
+
+		tracePropagator.Inject(ctx, propagation.HeaderCarrier(c.Writer.Header()))
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= 500 {
+			span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", status))
+		}
+		if len(c.Errors) > 0 {
+			span.RecordError(c.Errors.Last())
+		}
+	}
+}
+
+// gormSpanKey 保存在 Statement 实例设置中的 SQL span
+const gormSpanKey = "gormtool:trace_span"
+
+// RegisterTracing 注册 GORM 回调，为每条 SQL 语句创建 span（父 span 取自 WithContext 传入的 context）
+// span 属性包含 SQL 模板（不含参数值）、表名和影响行数，记录不存在不视为错误
+func (t *CRUDTool) RegisterTracing() error {
+	callbacks := t.DB.Callback()
+	errs := []error{
+		callbacks.Create().Before("gorm:create").Register("gormtool:trace_before", t.traceBefore("create")),
+		callbacks.Create().After("gorm:create").Register("gormtool:trace_after", t.traceAfter),
+		callbacks.Query().Before("gorm:query").Register("gormtool:trace_before", t.traceBefore("query")),
+		callbacks.Query().After("gorm:query").Register("gormtool:trace_after", t.traceAfter),
+		callbacks.Update().Before("gorm:update").Register("gormtool:trace_before", t.traceBefore("update")),
+		callbacks.Update().After("gorm:update").Register("gormtool:trace_after", t.traceAfter),
+		callbacks.Delete().Before("gorm:delete").Register("gormtool:trace_before", t.traceBefore("delete")),
+		callbacks.Delete().After("gorm:delete").Register("gormtool:trace_after", t.traceAfter),
+		callbacks.Row().Before("gorm:row").Register("gormtool:trace_before", t.traceBefore("row")),
+		callbacks.Row().After("gorm:row").Register("gormtool:trace_after", t.traceAfter),
+		callbacks.Raw().Before("gorm:raw").Register("gormtool:trace_before", t.traceBefore("raw")),
+		callbacks.Raw().After("gorm:raw").Register("gormtool:trace_after", t.traceAfter),
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("gormtool: 注册追踪回调失败: %w", err)
+	}
+	return nil
+}
+
+func (t *CRUDTool) traceBefore(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		_, span := t.tracer().Start(ctx, "gorm."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", db.Dialector.Name()),
+				attribute.String("db.operation.name", operation),
+			))
+		db.InstanceSet(gormSpanKey, span)
+	}
+}
+
+func (t *CRUDTool) traceAfter(db *gorm.DB) {
+	v, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span := v.(trace.Span)
+	defer span.End()
+
+	if span.IsRecording() {
+		span.SetAttributes(
+			attribute.String("db.query.text", db.Statement.SQL.String()),
+			attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+		)
+		if db.Statement.Table != "" {
+			span.SetAttributes(attribute.String("db.collection.name", db.Statement.Table))
+		}
+	}
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
+
+// TraceRedis 为 Redis 客户端添加追踪 Hook，每条命令（或每个 pipeline）一个 span
+// 只记录命令名和第一个键，不记录写入的值
+func (t *CRUDTool) TraceRedis(client redis.UniversalClient) {
+	client.AddHook(redisTracingHook{tool: t})
+}
+
+type redisTracingHook struct {
+	tool *CRUDTool
+}
+
+func (h redisTracingHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h redisTracingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, span := h.tool.tracer().Start(ctx, "redis."+cmd.Name(),
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(redisAttributes(cmd)...))
+		defer span.End()
+
+		err := next(ctx, cmd)
+		if err != nil && !errors.Is(err, redis.Nil) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		return err
+	}
+}
+
+func (h redisTracingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		ctx, span := h.tool.tracer().Start(ctx, "redis.pipeline",
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", "redis"),
+				attribute.Int("db.operation.batch.size", len(cmds)),
+			))
+		defer span.End()
+
+		err := next(ctx, cmds)
+		if err != nil && !errors.Is(err, redis.Nil) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		return err
+	}
+}
+
+func redisAttributes(cmd redis.Cmder) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("db.system", "redis"),
+		attribute.String("db.operation.name", cmd.Name()),
+	}
+	if args := cmd.Args(); len(args) > 1 {
+		attrs = append(attrs, attribute.String("db.redis.key", fmt.Sprint(args[1])))
+	}
+	return attrs
+}
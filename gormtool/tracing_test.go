@@ -0,0 +1,208 @@
+// gormtool\tracing_test.go
+package gormtool
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// useSpanRecorder 让 tool 使用记录所有 span 的 TracerProvider
+func useSpanRecorder(t *testing.T, tool *CRUDTool) *tracetest.SpanRecorder {
+	t.Helper()
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	t.Cleanup(func() { tp.Shutdown(context.Background()) })
+	tool.TracerProvider = tp
+	return sr
+}
+
+// spansByName 按名称索引已结束的 span，同名时保留最后一个
+func spansByName(sr *tracetest.SpanRecorder) map[string]sdktrace.ReadOnlySpan {
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, s := range sr.Ended() {
+		spans[s.Name()] = s
+	}
+	return spans
+}
+
+// spanAttr 返回 span 的属性值，不存在时返回无效的 Value
+func spanAttr(s sdktrace.ReadOnlySpan, key string) attribute.Value {
+	for _, kv := range s.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+// spanNames 返回已结束的 span 名称，用于失败信息
+func spanNames(sr *tracetest.SpanRecorder) []string {
+	var names []string
+	for _, s := range sr.Ended() {
+		names = append(names, s.Name())
+	}
+	return names
+}
+
+// 请求 span 继续上游链路，操作 span 和 SQL span 依次作为子 span
+func TestTracingSpans(t *testing.T) {
+	env := newTestEnv(t)
+	sr := useSpanRecorder(t, env.tool)
+	if err := env.tool.RegisterTracing(); err != nil {
+		t.Fatal(err)
+	}
+	env.seed(t, "a")
+	sr.Reset()
+
+	r := newTestRouter()
+	r.Use(RequestIDMiddleware(), env.tool.TracingMiddleware())
+	r.GET("/items/:id", func(c *gin.Context) { env.tool.GetByID(c, &testItem{}) })
+
+	const upstream = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := httptest.NewRequest(http.MethodGet, "/items/1", nil)
+	req.Header.Set("traceparent", upstream)
+	req.Header.Set(RequestIDHeader, "req-trace")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /items/1 = %d", w.Code)
+	}
+
+	spans := spansByName(sr)
+	server, op, sql := spans["GET /items/:id"], spans["get_by_id"], spans["gorm.query"]
+	if server == nil || op == nil || sql == nil {
+		t.Fatalf("spans = %v, want GET /items/:id、get_by_id、gorm.query", spanNames(sr))
+	}
+
+	if got := server.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("trace id = %s, 未继续上游链路", got)
+	}
+	if server.Parent().SpanID().String() != "00f067aa0ba902b7" || !server.Parent().IsRemote() {
+		t.Fatalf("server parent = %v", server.Parent())
+	}
+	if op.Parent().SpanID() != server.SpanContext().SpanID() || sql.Parent().SpanID() != op.SpanContext().SpanID() {
+		t.Fatal("get_by_id 应为请求 span 的子 span，gorm.query 应为 get_by_id 的子 span")
+	}
+	if tp := w.Header().Get("traceparent"); !strings.Contains(tp, "4bf92f3577b34da6a3ce929d0e0e4736") || !strings.Contains(tp, server.SpanContext().SpanID().String()) {
+		t.Fatalf("响应头 traceparent = %q", tp)
+	}
+
+	if server.SpanKind() != trace.SpanKindServer || sql.SpanKind() != trace.SpanKindClient {
+		t.Fatalf("span kind = %v, %v", server.SpanKind(), sql.SpanKind())
+	}
+	for _, check := range []struct {
+		span sdktrace.ReadOnlySpan
+		key  string
+		want string
+	}{
+		{server, "http.request.method", "GET"},
+		{server, "http.route", "/items/:id"},
+		{server, "url.path", "/items/1"},
+		{server, "http.request.id", "req-trace"},
+		{server, "http.response.status_code", "200"},
+		{op, "gormtool.operation", "get_by_id"},
+		{op, "gormtool.model", "test_items"},
+		{op, "gormtool.id", "1"},
+		{sql, "db.system", "sqlite"},
+		{sql, "db.operation.name", "query"},
+		{sql, "db.collection.name", "test_items"},
+	} {
+		if got := spanAttr(check.span, check.key).Emit(); got != check.want {
+			t.Errorf("%s %s = %q, want %q", check.span.Name(), check.key, got, check.want)
+		}
+	}
+	if text := spanAttr(sql, "db.query.text").AsString(); !strings.Contains(text, "test_items") || strings.Contains(text, "'a'") {
+		t.Errorf("db.query.text = %q", text)
+	}
+	if server.Status().Code != codes.Unset || op.Status().Code != codes.Unset {
+		t.Errorf("成功请求的 span 状态 = %v, %v", server.Status(), op.Status())
+	}
+}
+
+// 记录不存在时操作 span 标记为错误，SQL span 不视为错误，请求 span 只在 5xx 时为错误
+func TestTracingNotFound(t *testing.T) {
+	env := newTestEnv(t)
+	sr := useSpanRecorder(t, env.tool)
+	if err := env.tool.RegisterTracing(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := newTestRouter()
+	r.Use(env.tool.TracingMiddleware())
+	r.GET("/items/:id", func(c *gin.Context) { env.tool.GetByID(c, &testItem{}) })
+	if w, _ := doRequest(t, r, http.MethodGet, "/items/999", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("GET /items/999 = %d", w.Code)
+	}
+
+	spans := spansByName(sr)
+	op := spans["get_by_id"]
+	if op == nil || op.Status().Code != codes.Error || spanAttr(op, "gormtool.error_type").AsString() != string(ErrKindNotFound) {
+		t.Fatalf("get_by_id span = %+v", op)
+	}
+	if len(op.Events()) == 0 || op.Events()[0].Name != "exception" {
+		t.Fatalf("get_by_id 未记录错误事件: %v", op.Events())
+	}
+	if sql := spans["gorm.query"]; sql == nil || sql.Status().Code != codes.Unset {
+		t.Fatalf("gorm.query span 状态 = %+v", sql)
+	}
+	server := spans["GET /items/:id"]
+	if server == nil || server.Status().Code != codes.Unset || spanAttr(server, "http.response.status_code").AsInt64() != 404 {
+		t.Fatalf("请求 span = %+v", server)
+	}
+	if spans["GET /items/:id"].SpanContext().TraceID() != op.SpanContext().TraceID() {
+		t.Fatal("没有 traceparent 时应新建链路并共享 trace id")
+	}
+}
+
+// Redis 命令和 pipeline 各一个 span，只记录命令名和第一个键
+func TestTraceRedis(t *testing.T) {
+	tool := NewCRUDTool(nil, nil, nil)
+	sr := useSpanRecorder(t, tool)
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	tool.TraceRedis(client)
+
+	ctx := context.Background()
+	if err := client.Set(ctx, "user:1", "secret", 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Get(ctx, "missing").Err(); err != redis.Nil {
+		t.Fatalf("Get(missing) err = %v", err)
+	}
+	pipe := client.Pipeline()
+	pipe.Del(ctx, "a")
+	pipe.Del(ctx, "b")
+	if _, err := pipe.Exec(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := spansByName(sr)
+	set := spans["redis.set"]
+	if set == nil || spanAttr(set, "db.redis.key").AsString() != "user:1" || spanAttr(set, "db.system").AsString() != "redis" {
+		t.Fatalf("redis.set span = %+v; spans = %v", set, spanNames(sr))
+	}
+	for _, kv := range set.Attributes() {
+		if strings.Contains(kv.Value.Emit(), "secret") {
+			t.Fatalf("span 属性包含写入的值: %v", kv)
+		}
+	}
+	if get := spans["redis.get"]; get == nil || get.Status().Code != codes.Unset {
+		t.Fatalf("redis.Nil 不应视为错误: %+v", get)
+	}
+	if p := spans["redis.pipeline"]; p == nil || spanAttr(p, "db.operation.batch.size").AsInt64() != 2 {
+		t.Fatalf("redis.pipeline span = %+v", p)
+	}
+}
@@ -1,24 +1,32 @@
 package main
 
-// ubuntu 后台执行的方法 nohup ./eco_back > eco_back.log 2>&1 &
+// ubuntu 后台执行的方法 LOG_FILE=logs/eco_back.log nohup ./eco_back > /dev/null 2>&1 &
 import (
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/studieren/eco_back/gormtool"
 	"github.com/studieren/eco_back/models"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"gorm.io/driver/sqlite"
 
 	// "github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
 )
 
 var (
 	db *gorm.DB
 	// rdb    *redis.Client
-	cruder *gormtool.CRUDTool
+	cruder   *gormtool.CRUDTool
+	userRepo *gormtool.Repository[models.User]
 )
 
 // 初始化 DB、Redis、CRUDTool
@@ -31,22 +39,98 @@ func init() {
 		log.Fatal(err)
 	}
 	// 自动迁移
-	db.AutoMigrate(&models.User{}, &models.Profile{}, &models.Tag{})
+	db.AutoMigrate(&models.User{}, &models.Profile{}, &models.Tag{}, &models.Order{})
 
 	// rdb = redis.NewClient(&redis.Options{Addr: "127.0.0.1:6379"})
-	// cruder = gormtool.NewCRUDTool(db, rdb, nil) // 使用默认 logger, 使用 Redis
-	cruder = gormtool.NewCRUDTool(db, nil, nil) // 不使用 Redis
+	// cruder = gormtool.NewCRUDTool(db, gormtool.NewRedisCache(rdb), nil) // 使用默认 logger, 使用 Redis
+	// 多实例部署：进程内 L1 + Redis L2，写入时通过 pub/sub 通知其他实例
+	// cruder = gormtool.NewCRUDTool(db, gormtool.NewTieredCache(gormtool.NewMemoryCache(10000), gormtool.NewRedisCache(rdb), "", 30*time.Second, nil), nil)
+	// 日志：LOG_LEVEL=debug|info|warn|error，LOG_FORMAT=text|json，
+	// LOG_FILE 不为空时写入文件（超过 100MB 或每天切换，保留 7 个旧文件）
+	logger, err := gormtool.NewLogger(gormtool.LogConfig{
+		Level:       os.Getenv("LOG_LEVEL"),
+		Format:      os.Getenv("LOG_FORMAT"),
+		File:        os.Getenv("LOG_FILE"),
+		MaxSizeMB:   100,
+		RotateEvery: 24 * time.Hour,
+		MaxBackups:  7,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	slog.SetDefault(logger.Slog())                                            // log.Printf 也写入同一个日志
+	cruder = gormtool.NewCRUDTool(db, gormtool.NewMemoryCache(10000), logger) // 使用进程内缓存
+	// 成功的单条查询只记录 10% 的日志，失败总是记录
+	cruder.LogSampling = map[string]float64{"get_by_id": 0.1}
+	cruder.CacheNamespace = "eco_back"
+	// 缓存过期时间 ±10% 随机，快过期的热点记录提前刷新
+	cruder.CacheTTLJitter = 0.1
+	cruder.CacheEarlyRefreshBeta = 1.0
+	// 缓存列表查询，任何写入都会使相关列表失效
+	cruder.ListCacheTTL = 30 * time.Second
+	// 删除缓存 500ms 后再删除一次，清除并发读取写回的旧数据
+	cruder.CacheDoubleDeleteDelay = 500 * time.Millisecond
+	// 缓存调用超时 100ms，连续失败 5 次后 10 秒内不访问缓存
+	cruder.CacheTimeout = 100 * time.Millisecond
+	cruder.CacheBreakerThreshold = 5
+	cruder.CacheBreakerCooldown = 10 * time.Second
+	// SQL 日志通过 cruder.Logger 输出：默认只记录失败和超过 200ms 的慢查询，LOG_LEVEL=debug 时记录所有语句
+	// EXPLAIN_SLOW_QUERIES=1 时慢查询附带查询计划（会再执行一次语句的 EXPLAIN，仅用于开发调试）
+	cruder.SlowQueryThreshold = 200 * time.Millisecond
+	cruder.ExplainSlowQueries = os.Getenv("EXPLAIN_SLOW_QUERIES") == "1"
+	sqlLogLevel := gormlogger.Warn
+	if os.Getenv("LOG_LEVEL") == "debug" {
+		sqlLogLevel = gormlogger.Info
+	}
+	db.Logger = cruder.SQLLogger(sqlLogLevel)
+	userRepo = gormtool.NewRepository[models.User](cruder)
+
+	// 链路追踪：TRACE_STDOUT=1 时把 span 打印到标准输出（本地调试），生产环境换成 OTLP 等导出器
+	if os.Getenv("TRACE_STDOUT") != "" {
+		exporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			log.Fatal(err)
+		}
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	}
+	if err := cruder.RegisterTracing(); err != nil {
+		log.Printf("SQL 追踪不可用: %v", err)
+	}
+	// cruder.TraceRedis(rdb) // 使用 Redis 时追踪每条命令
+
+	// 查询字段别名（前端使用驼峰命名）
+	cruder.SetFieldPolicy(&models.User{}, gormtool.FieldPolicy{
+		Aliases: map[string]string{"createdAt": "created_at", "updatedAt": "updated_at"},
+	})
+
+	// 全文搜索（-tags sqlite_fts5 编译时使用 FTS5，否则退化为 LIKE 匹配）
+	for model, fields := range map[interface{}][]string{
+		&models.User{}:    {"Name"},
+		&models.Profile{}: {"Bio"},
+		&models.Tag{}:     {"Name"},
+	} {
+		if err := cruder.RegisterSearch(model, fields...); err != nil {
+			log.Printf("全文搜索不可用: %v", err)
+		}
+	}
 }
 
 func main() {
-	r := gin.Default()
+	// 不使用 gin.Default() 的文本日志和 Recovery：访问日志通过 cruder.Logger 输出，panic 返回 JSON Response
+	r := gin.New()
+	r.Use(gormtool.RequestIDMiddleware())
+	r.Use(cruder.AccessLogMiddleware("/metrics"))
 	r.Use(cors.Default())
+	r.Use(cruder.TracingMiddleware())
+	r.Use(cruder.MetricsMiddleware())
+	r.Use(cruder.RecoveryMiddleware())
 	// 1) 事务级联创建：User + Profile + Tags
 	r.POST("/users", createUserWithEverything)
 
 	// 2) 查询所有
 	r.GET("/users", getAllUsers)
 	r.GET("/users/:id", getUserByID)
+	r.POST("/users/query", queryUsers)
 
 	// 3) 更新 User + 同步更新关联 Tags（事务 + 缓存失效）
 	r.PUT("/users/:id", updateUserWithTags)
@@ -60,8 +144,20 @@ func main() {
 	// 6) 批量硬删除（危险操作演示）
 	r.DELETE("/users/batch/hard", batchHardDelete)
 
-	// 7) 指标监控
-	r.GET("/metrics", cruder.GetMetrics)
+	// 7) 指标监控：Prometheus 文本格式，JSON 格式在 /metrics/json
+	r.GET("/metrics", cruder.PrometheusMetrics)
+	r.GET("/metrics/json", cruder.GetMetrics)
+
+	// 8) 缓存管理（生产环境需要加鉴权）
+	admin := r.Group("/admin")
+	admin.DELETE("/cache", cruder.FlushCache)
+	admin.GET("/cache/:table/:id", cruder.InspectCache)
+	admin.DELETE("/cache/:table/:id", cruder.EvictCache)
+
+	// 9) 声明式注册的资源路由
+	cruder.RegisterResource(r, "/profiles", &models.Profile{}, gormtool.ResourceOptions{})
+	cruder.RegisterResource(r, "/tags", &models.Tag{}, gormtool.ResourceOptions{})
+	cruder.RegisterResource(r, "/orders", &models.Order{}, gormtool.ResourceOptions{})
 
 	r.Run(":1234")
 }
@@ -79,12 +175,15 @@ func createUserWithEverything(c *gin.Context) {
 		Tags    []models.Tag   `json:"tags"`
 	}
 	var req payload
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"msg": err.Error()})
+	_, span := otel.Tracer("eco_back").Start(c.Request.Context(), "bind")
+	err := gormtool.BindJSON(c, &req)
+	span.End()
+	if err != nil {
+		gormtool.RespondError(c, err)
 		return
 	}
 
-	err := cruder.WithTransaction(c.Request.Context(), func(tx *gorm.DB) error {
+	err = cruder.WithTransaction(c.Request.Context(), func(tx *gorm.DB) error {
 		// 1. 创建 user
 		if err := tx.Create(&req.User).Error; err != nil {
 			return err
@@ -95,13 +194,23 @@ func createUserWithEverything(c *gin.Context) {
 			return err
 		}
 		// 3. 创建/附加 tags
-		return tx.Model(&req.User).Association("Tags").Append(req.Tags)
+		if err := tx.Model(&req.User).Association("Tags").Append(req.Tags); err != nil {
+			return err
+		}
+		// 4. 清除缓存：提交成功后执行，回滚时丢弃
+		ctx := tx.Statement.Context
+		cruder.InvalidateRecord(ctx, &models.User{}, req.User.ID)
+		cruder.InvalidateRecord(ctx, &models.Profile{}, req.Profile.ID)
+		for _, tag := range req.Tags {
+			cruder.InvalidateRecord(ctx, &models.Tag{}, tag.ID)
+		}
+		return nil
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"msg": err.Error()})
+		gormtool.RespondError(c, gormtool.NewError(gormtool.ErrKindDB, "create_user_with_everything", "创建失败", err))
 		return
 	}
-	c.JSON(http.StatusCreated, gin.H{"data": req.User})
+	gormtool.Respond(c, http.StatusCreated, "创建成功", req.User)
 }
 
 /*
@@ -110,20 +219,16 @@ func createUserWithEverything(c *gin.Context) {
 
 ------------------------------------------------
 */
-// func queryUsers(c *gin.Context) {
-// 	qb := &gormtool.QueryBuilder{
-// 		Conditions: []gormtool.QueryCondition{
-// 			// {Field: "age", Operator: ">=", Value: 18},
-// 			{Field: "name", Operator: "LIKE", Value: c.Query("name")},
-// 		},
-// 		Sorts: []gormtool.SortCondition{
-// 			{Field: "created_at", Direction: "DESC"},
-// 		},
-// 		Preloads: []string{"Profile", "Tags"},
-// 	}
-// 	var users []models.User
-// 	_ = cruder.GetByQueryBuilder(c, &users, qb) // 出错已在内部返回
-// }
+// 请求体中的字段、操作符、排序方向都会按 User 的 schema 校验，未知字段返回 400
+func queryUsers(c *gin.Context) {
+	var qb gormtool.QueryBuilder
+	if err := gormtool.BindJSON(c, &qb); err != nil {
+		gormtool.RespondError(c, err)
+		return
+	}
+	var users []models.User
+	_ = cruder.GetByQueryBuilder(c, &users, &qb) // 出错已在内部返回
+}
 
 func getAllUsers(c *gin.Context) {
 	var users []models.User
@@ -131,22 +236,21 @@ func getAllUsers(c *gin.Context) {
 }
 
 func getUserByID(c *gin.Context) {
-	var user models.User
-
-	// 调用 CRUD 方法（注意：GetByID 内部已处理 HTTP 响应）
-	err := cruder.GetByID(c, &user)
+	id, err := gormtool.ParseID(c)
+	if err != nil {
+		gormtool.RespondError(c, err)
+		return
+	}
 
-	// 记录错误（便于排查问题，即使响应已发送）
+	// 服务层只返回数据和错误，不写响应
+	user, err := userRepo.GetByID(c.Request.Context(), id)
 	if err != nil {
-		// 可以使用日志库（如 zap、logrus）记录错误详情
-		log.Printf("获取用户失败: %v, ID: %s", err, c.Param("id"))
-		// c.JSON(http.StatusNotFound, gin.H{"msg": err.Error()})
-		// 无需再次发送响应，因为 GetByID 已处理
+		gormtool.RespondError(c, err)
 		return
 	}
 
-	// 若需要在成功后添加额外逻辑（如统计、权限二次校验等）
-	// 注意：此时响应已由 GetByID 发送，不能再调用 c.JSON
+	// 在这里可以追加额外逻辑（如统计、权限二次校验、数据脱敏等）后再返回
+	gormtool.Respond(c, http.StatusOK, "查询成功", user)
 }
 
 /*
@@ -156,32 +260,40 @@ func getUserByID(c *gin.Context) {
 ------------------------------------------------
 */
 func updateUserWithTags(c *gin.Context) {
+	id, err := gormtool.ParseID(c)
+	if err != nil {
+		gormtool.RespondError(c, err)
+		return
+	}
 	var user models.User
-	// 先查出来（软删除除外）
-	if err := cruder.DB.First(&user, c.Param("id")).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"msg": err.Error()})
+	// 先查出来（软删除除外），不存在时返回 404
+	if _, err := cruder.FindByID(c.Request.Context(), &user, id, gormtool.FindOptions{NoCache: true}); err != nil {
+		gormtool.RespondError(c, err)
 		return
 	}
 	// 绑定 JSON
-	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"msg": err.Error()})
+	if err := gormtool.BindJSON(c, &user); err != nil {
+		gormtool.RespondError(c, err)
 		return
 	}
 	// 事务更新
-	err := cruder.WithTransaction(c.Request.Context(), func(tx *gorm.DB) error {
+	err = cruder.WithTransaction(c.Request.Context(), func(tx *gorm.DB) error {
 		if err := tx.Save(&user).Error; err != nil {
 			return err
 		}
 		// 前端把完整的 tags 传过来 -> 直接 Replace
-		return tx.Model(&user).Association("Tags").Replace(user.Tags)
+		if err := tx.Model(&user).Association("Tags").Replace(user.Tags); err != nil {
+			return err
+		}
+		// 清除缓存：提交成功后执行，回滚时丢弃
+		cruder.InvalidateRecord(tx.Statement.Context, &models.User{}, user.ID)
+		return nil
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"msg": err.Error()})
+		gormtool.RespondError(c, gormtool.NewError(gormtool.ErrKindDB, "update_user_with_tags", "更新失败", err))
 		return
 	}
-	// 清除缓存
-	cruder.DeleteFromCache(c.Request.Context(), cruder.GenerateCacheKey(&models.User{}, user.ID))
-	c.JSON(http.StatusOK, gin.H{"data": user})
+	gormtool.Respond(c, http.StatusOK, "更新成功", user)
 }
 
 /*
@@ -217,16 +329,23 @@ func batchHardDelete(c *gin.Context) {
 		IDs []uint `json:"ids"`
 	}
 	var ids IDs
-	if err := c.ShouldBindJSON(&ids); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"msg": err.Error()})
+	if err := gormtool.BindJSON(c, &ids); err != nil {
+		gormtool.RespondError(c, err)
 		return
 	}
 	err := cruder.WithTransaction(c.Request.Context(), func(tx *gorm.DB) error {
-		return tx.Unscoped().Delete(&models.User{}, ids.IDs).Error
+		if err := tx.Unscoped().Delete(&models.User{}, ids.IDs).Error; err != nil {
+			return err
+		}
+		// 清除缓存：提交成功后执行，回滚时丢弃
+		for _, id := range ids.IDs {
+			cruder.InvalidateRecord(tx.Statement.Context, &models.User{}, id)
+		}
+		return nil
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"msg": err.Error()})
+		gormtool.RespondError(c, gormtool.NewError(gormtool.ErrKindDB, "batch_hard_delete", "删除失败", err))
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"affected": len(ids.IDs)})
+	gormtool.Respond(c, http.StatusOK, "删除成功", gin.H{"affected": len(ids.IDs)})
 }